@@ -0,0 +1,146 @@
+package geopard
+
+import "strings"
+
+//PostalAddress is a normalized postal address assembled from the address
+//components of a result. Fields without a matching component are empty.
+type PostalAddress struct {
+	StreetNumber string
+	Route        string
+	Locality     string
+	AdminArea    string
+	PostalCode   string
+	CountryCode  string
+	CountryName  string
+}
+
+//PostalAddress maps the result's address components to a PostalAddress.
+//The component types used are:
+//
+//	StreetNumber  long name of street_number
+//	Route         long name of route
+//	Locality      long name of locality, or postal_town if there is none
+//	AdminArea     long name of administrative_area_level_1
+//	PostalCode    long name of postal_code
+//	CountryCode   short name of country (ISO 3166-1 alpha-2)
+//	CountryName   long name of country
+func (r GResult) PostalAddress() PostalAddress {
+	addr := PostalAddress{
+		StreetNumber: r.componentLong("street_number"),
+		Route:        r.componentLong("route"),
+		Locality:     r.componentLong("locality"),
+		AdminArea:    r.componentLong("administrative_area_level_1"),
+		PostalCode:   r.componentLong("postal_code"),
+	}
+	if addr.Locality == "" {
+		addr.Locality = r.componentLong("postal_town")
+	}
+	if c, ok := r.component("country"); ok {
+		addr.CountryCode = c.Short
+		addr.CountryName = c.Long
+	}
+	return addr
+}
+
+//component returns the first address component having the given type.
+func (r GResult) component(typ string) (GAddrComponent, bool) {
+	for _, c := range r.AddrComponents {
+		for _, t := range c.Types {
+			if t == typ {
+				return c, true
+			}
+		}
+	}
+	return GAddrComponent{}, false
+}
+
+//componentLong returns the long name of the first address component
+//having the given type or an empty string.
+func (r GResult) componentLong(typ string) string {
+	c, _ := r.component(typ)
+	return c.Long
+}
+
+//ShortAddress returns a compact address label like "1600 Amphitheatre
+//Parkway, Mountain View". It consists of the street, i.e. the street number
+//and route joined by a space, and the locality (or postal_town) joined by
+//", ". Missing components are left out together with their separator.
+//Unlike FormattedAddr it never contains the postal code or country.
+//
+//The order follows the conventions of the result's country:
+//
+//	countries                                     example
+//	default, e.g. US, GB, FR, CA, AU              1 Main St, Springfield
+//	AT, BE, BR, CH, CZ, DE, DK, ES, IT, NL,       Hauptstraße 1, Berlin
+//	NO, PL, SE
+//	CN, JP, KR, TW                                Shibuya, Dogenzaka 1
+func (r GResult) ShortAddress() string {
+	return r.ShortAddressSep(defaultAddressSeparator)
+}
+
+//ShortAddressSep works like ShortAddress but joins street and locality
+//with sep.
+func (r GResult) ShortAddressSep(sep string) string {
+	addr := r.PostalAddress()
+	switch addressOrders[addr.CountryCode] {
+	case routeFirst:
+		return joinNonEmpty(sep, joinNonEmpty(" ", addr.Route, addr.StreetNumber), addr.Locality)
+	case localityFirst:
+		return joinNonEmpty(sep, addr.Locality, joinNonEmpty(" ", addr.Route, addr.StreetNumber))
+	}
+	return joinNonEmpty(sep, joinNonEmpty(" ", addr.StreetNumber, addr.Route), addr.Locality)
+}
+
+//ShortAddress returns the ShortAddress of res with the parts separated by
+//Options.AddressSeparator.
+func (r *requestProcessor) ShortAddress(res GResult) string {
+	return res.ShortAddressSep(r.addrSep)
+}
+
+//addressOrder is the order of the parts of a ShortAddress.
+type addressOrder int
+
+const (
+	//numberFirst is "number route, locality"
+	numberFirst addressOrder = iota
+	//routeFirst is "route number, locality"
+	routeFirst
+	//localityFirst is "locality, route number"
+	localityFirst
+)
+
+//addressOrders maps ISO 3166-1 country codes to their address order.
+//Countries not listed use numberFirst.
+var addressOrders = map[string]addressOrder{
+	"AT": routeFirst,
+	"BE": routeFirst,
+	"BR": routeFirst,
+	"CH": routeFirst,
+	"CZ": routeFirst,
+	"DE": routeFirst,
+	"DK": routeFirst,
+	"ES": routeFirst,
+	"IT": routeFirst,
+	"NL": routeFirst,
+	"NO": routeFirst,
+	"PL": routeFirst,
+	"SE": routeFirst,
+	"CN": localityFirst,
+	"JP": localityFirst,
+	"KR": localityFirst,
+	"TW": localityFirst,
+}
+
+//defaultAddressSeparator is the default for Options.AddressSeparator.
+const defaultAddressSeparator = ", "
+
+//joinNonEmpty joins all non-empty parts with sep.
+func joinNonEmpty(sep string, parts ...string) string {
+	nonEmpty := parts[:0:0]
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
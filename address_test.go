@@ -0,0 +1,29 @@
+package geopard
+
+import "testing"
+
+func TestShortAddressSeparator(t *testing.T) {
+	res := GResult{AddrComponents: []GAddrComponent{
+		{"1", "1", []string{"street_number"}},
+		{"Unter den Linden", "Unter den Linden", []string{"route"}},
+		{"Berlin", "Berlin", []string{"locality", "political"}},
+		{"Germany", "DE", []string{"country", "political"}},
+	}}
+	if got, want := res.ShortAddress(), "Unter den Linden 1, Berlin"; got != want {
+		t.Errorf("ShortAddress() = %q, want %q", got, want)
+	}
+	if got, want := res.ShortAddressSep(" · "), "Unter den Linden 1 · Berlin"; got != want {
+		t.Errorf("ShortAddressSep() = %q, want %q", got, want)
+	}
+
+	r := New(Options{AddressSeparator: " / "})
+	defer r.Close()
+	if got, want := r.ShortAddress(res), "Unter den Linden 1 / Berlin"; got != want {
+		t.Errorf("processor ShortAddress() = %q, want %q", got, want)
+	}
+	def := New(Options{})
+	defer def.Close()
+	if got, want := def.ShortAddress(res), "Unter den Linden 1, Berlin"; got != want {
+		t.Errorf("default processor ShortAddress() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,252 @@
+package geopard
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//BatchItem pairs an input of a batch with its outcome.
+type BatchItem struct {
+	Input    string
+	Response GResponse
+	Err      error
+}
+
+//BatchDedup selects how GeocodeBatch handles duplicate addresses.
+type BatchDedup int
+
+const (
+	//DedupNone sends one request per address.
+	DedupNone BatchDedup = iota
+	//DedupConsecutive sends one request per run of identical consecutive
+	//addresses, which suits sorted input. Runs are handed to the workers as
+	//they are found, so it needs no extra memory per distinct address.
+	//Duplicates that aren't adjacent are sent again.
+	DedupConsecutive
+	//DedupAll sends one request per distinct address, using a map of all
+	//addresses seen.
+	DedupAll
+)
+
+//GeocodeBatch geocodes all given addresses concurrently while obeying the
+//rate limit. The returned items are indexed like the input: items[i] always
+//belongs to addresses[i], no matter in which order the requests complete.
+//Identical input therefore always yields identically ordered output.
+//Duplicate addresses are collapsed according to Options.BatchDedup, every
+//position receiving the outcome of the shared request.
+func (r *requestProcessor) GeocodeBatch(ctx context.Context, addresses []string, opts ...RequestOption) []BatchItem {
+	items := make([]BatchItem, len(addresses))
+	jobs := make(chan batchJob)
+	go r.batchJobs(addresses, jobs)
+
+	workers := r.batchWorkers(len(addresses))
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				//every worker writes only to the positions of its own jobs
+				//so no locking is needed
+				resp, err := r.GeocodeContext(ctx, addresses[job.start], opts...)
+				job.each(func(i int) {
+					items[i] = BatchItem{Input: addresses[i], Response: resp, Err: err}
+					if i != job.start {
+						//don't share the results slice between items
+						items[i].Response.Results = append([]GResult(nil), resp.Results...)
+					}
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	return items
+}
+
+//batchJob is a single request of a batch. Its outcome belongs to the run
+//of positions [start, end) and to the positions in dups.
+type batchJob struct {
+	start, end int
+	dups       []int
+}
+
+//each calls f for every position of the job.
+func (j batchJob) each(f func(int)) {
+	for i := j.start; i < j.end; i++ {
+		f(i)
+	}
+	for _, i := range j.dups {
+		f(i)
+	}
+}
+
+//batchJobs sends the requests needed for addresses according to the
+//configured BatchDedup to jobs and closes it. Runs of DedupConsecutive
+//are sent as soon as they end, so only DedupAll keeps state for all
+//addresses.
+func (r *requestProcessor) batchJobs(addresses []string, jobs chan<- batchJob) {
+	defer close(jobs)
+	switch r.batchDedup {
+	case DedupConsecutive:
+		for start := 0; start < len(addresses); {
+			end := start + 1
+			for end < len(addresses) && addresses[end] == addresses[start] {
+				end++
+			}
+			jobs <- batchJob{start: start, end: end}
+			start = end
+		}
+	case DedupAll:
+		seen := map[string]int{}
+		groups := []batchJob{}
+		for i, address := range addresses {
+			if j, ok := seen[address]; ok {
+				groups[j].dups = append(groups[j].dups, i)
+				continue
+			}
+			seen[address] = len(groups)
+			groups = append(groups, batchJob{start: i, end: i + 1})
+		}
+		for _, job := range groups {
+			jobs <- job
+		}
+	default:
+		for i := range addresses {
+			jobs <- batchJob{start: i, end: i + 1}
+		}
+	}
+}
+
+//batchWorkers returns the number of workers used for n requests. More
+//workers than requests per second would only wait on the throttle.
+func (r *requestProcessor) batchWorkers(n int) int {
+	if r.maxQueriesPerSec < n {
+		return r.maxQueriesPerSec
+	}
+	return n
+}
+
+//Outcome is either the response of a request or its error.
+type Outcome struct {
+	Value GResponse
+	Err   error
+}
+
+//Unwrap returns the response and error of the outcome.
+func (o Outcome) Unwrap() (GResponse, error) {
+	return o.Value, o.Err
+}
+
+//GeocodeStream works like GeocodeBatch but emits the outcomes on the
+//returned channel as soon as they are available, in the order of addresses.
+//The channel is closed after the last outcome. The caller must receive all
+//outcomes; canceling ctx makes the remaining ones fail quickly.
+func (r *requestProcessor) GeocodeStream(ctx context.Context, addresses []string, opts ...RequestOption) <-chan Outcome {
+	out := make(chan Outcome)
+	//one buffered slot per address lets workers run ahead of the receiver
+	slots := make([]chan Outcome, len(addresses))
+	for i := range slots {
+		slots[i] = make(chan Outcome, 1)
+	}
+
+	indices := make(chan int)
+	for w := r.batchWorkers(len(addresses)); w > 0; w-- {
+		go func() {
+			for i := range indices {
+				resp, err := r.GeocodeContext(ctx, addresses[i], opts...)
+				slots[i] <- Outcome{Value: resp, Err: err}
+			}
+		}()
+	}
+	go func() {
+		for i := range addresses {
+			indices <- i
+		}
+		close(indices)
+	}()
+
+	go func() {
+		defer close(out)
+		for _, slot := range slots {
+			out <- <-slot
+		}
+	}()
+	return out
+}
+
+//BatchToPointMap maps every successfully geocoded input of a batch to the
+//location of its best result, which is the most precise one as ordered by
+//GResponse.ByPrecision. Inputs that failed, including those without
+//results, are omitted from the map.
+func BatchToPointMap(items []BatchItem) map[string]GPoint {
+	points := make(map[string]GPoint, len(items))
+	for _, item := range items {
+		if item.Err != nil || len(item.Response.Results) == 0 {
+			continue
+		}
+		points[item.Input] = item.Response.ByPrecision()[0].Geometry.Location
+	}
+	return points
+}
+
+//InputErrors is returned by GeocodeUniquePlaces if some inputs failed. It
+//maps each failed input to its error.
+type InputErrors map[string]error
+
+func (e InputErrors) Error() string {
+	inputs := make([]string, 0, len(e))
+	for input := range e {
+		inputs = append(inputs, input)
+	}
+	sort.Strings(inputs)
+
+	msgs := make([]string, len(inputs))
+	for i, input := range inputs {
+		msgs[i] = strconv.Quote(input) + ": " + e[input].Error()
+	}
+	return "geocoding failed for " + strings.Join(msgs, "; ")
+}
+
+//GeocodeUniquePlaces geocodes all addresses with GeocodeBatch and returns
+//one result per distinct place id, in the order the places first occurred.
+//Only the first result of every address is considered. If several
+//addresses resolve to the same place, the result with the best Quality is
+//kept, the earliest one on ties. Results without a place id are dropped.
+//If any address fails, including with ErrZeroResults, the places found for
+//the others are returned together with an InputErrors holding the errors
+//per address.
+func (r *requestProcessor) GeocodeUniquePlaces(ctx context.Context, addresses []string, opts ...RequestOption) ([]GResult, error) {
+	places := []GResult{}
+	index := map[PlaceID]int{}
+	errs := InputErrors{}
+	for _, item := range r.GeocodeBatch(ctx, addresses, opts...) {
+		if item.Err != nil {
+			errs[item.Input] = item.Err
+			continue
+		}
+		if len(item.Response.Results) == 0 {
+			continue
+		}
+		res := item.Response.Results[0]
+		if res.PlaceId == "" {
+			continue
+		}
+		if i, ok := index[res.PlaceId]; ok {
+			if res.Quality() > places[i].Quality() {
+				places[i] = res
+			}
+			continue
+		}
+		index[res.PlaceId] = len(places)
+		places = append(places, res)
+	}
+
+	if len(errs) > 0 {
+		return places, errs
+	}
+	return places, nil
+}
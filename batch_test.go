@@ -0,0 +1,66 @@
+package geopard
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//reverseServer answers the address "a<n>" after (10-n)*10ms so later
+//addresses complete first.
+func reverseServer(requests *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		address := req.URL.Query().Get("address")
+		n, _ := strconv.Atoi(strings.TrimPrefix(address, "a"))
+		time.Sleep(time.Duration(10-n) * 10 * time.Millisecond)
+		fmt.Fprintf(w, `{"status":"OK","results":[{"formatted_address":%q,"place_id":%q}]}`, address, address)
+	}))
+}
+
+func TestGeocodeBatchOrder(t *testing.T) {
+	var requests atomic.Int32
+	srv := reverseServer(&requests)
+	defer srv.Close()
+
+	tests := map[BatchDedup]struct {
+		addresses []string
+		requests  int32
+	}{
+		DedupNone:        {[]string{"a0", "a1", "a2", "a3", "a4", "a5", "a6", "a7", "a8", "a9"}, 10},
+		DedupConsecutive: {[]string{"a0", "a0", "a1", "a2", "a2", "a2", "a3", "a1", "a4", "a4"}, 6},
+		DedupAll:         {[]string{"a0", "a1", "a0", "a2", "a1", "a3", "a3", "a4", "a0", "a5"}, 6},
+	}
+	for dedup, tt := range tests {
+		addresses := tt.addresses
+		requests.Store(0)
+		r := New(Options{BaseURL: srv.URL + "/?", MaxQueriesPerSec: 1000, BatchDedup: dedup})
+		items := r.GeocodeBatch(context.Background(), addresses)
+		r.Close()
+
+		if got := requests.Load(); got != tt.requests {
+			t.Errorf("dedup %d: sent %d requests, want %d", dedup, got, tt.requests)
+		}
+
+		if len(items) != len(addresses) {
+			t.Fatalf("dedup %d: got %d items, want %d", dedup, len(items), len(addresses))
+		}
+		for i, item := range items {
+			if item.Err != nil {
+				t.Fatalf("dedup %d: item %d: %v", dedup, i, item.Err)
+			}
+			if item.Input != addresses[i] {
+				t.Errorf("dedup %d: items[%d].Input = %q, want %q", dedup, i, item.Input, addresses[i])
+			}
+			if len(item.Response.Results) != 1 || item.Response.Results[0].FormattedAddr != addresses[i] {
+				t.Errorf("dedup %d: items[%d] has the response of another address: %+v", dedup, i, item.Response.Results)
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package geopard
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+//volatileParams are the query parameters that don't influence the response
+//and are left out of cache keys.
+var volatileParams = []string{"key", "signature", "channel", "sessiontoken"}
+
+//CacheKey returns the key under which the response cache (see
+//Options.CacheTTL) stores a request with the given query parameters. The
+//parameters are sorted by name and the ones not affecting the response
+//(key, signature, channel and sessiontoken) are left out, so requests made
+//with different api keys or sessions share an entry. External caches
+//should use the same key to stay consistent with the internal one.
+func CacheKey(params url.Values) string {
+	stripped := make(url.Values, len(params))
+	for k, v := range params {
+		stripped[k] = v
+	}
+	for _, k := range volatileParams {
+		delete(stripped, k)
+	}
+	return stripped.Encode()
+}
+
+//cacheEntry is a cached response together with the data needed to
+//revalidate it.
+type cacheEntry struct {
+	Response GResponse `json:"response"`
+	ETag     string    `json:"etag,omitempty"`
+	Stored   time.Time `json:"stored"`
+}
+
+//cache is a concurrency safe in-memory response cache. It is shared by
+//all clones of a request processor.
+type cache struct {
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+//newCache returns a cache for the given time to live or nil if ttl
+//disables caching.
+func newCache(ttl time.Duration) *cache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &cache{ttl: ttl, now: time.Now, entries: map[string]cacheEntry{}}
+}
+
+//get returns the entry for key, regardless of whether it is fresh. The
+//results are copied so callers may reorder or filter them.
+func (c *cache) get(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	entry.Response.Results = append([]GResult(nil), entry.Response.Results...)
+	return entry, ok
+}
+
+//put stores the response for key.
+func (c *cache) put(key string, entry cacheEntry) {
+	entry.Stored = c.now()
+	entry.Response.Results = append([]GResult(nil), entry.Response.Results...)
+
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+}
+
+//fresh reports whether the entry may be used without revalidation.
+func (c *cache) fresh(entry cacheEntry) bool {
+	return c.age(entry) < c.ttl
+}
+
+//age returns the time since the entry was stored.
+func (c *cache) age(entry cacheEntry) time.Duration {
+	return c.now().Sub(entry.Stored)
+}
+
+//cacheFile is the content of a file written by SaveCache.
+type cacheFile struct {
+	Version int                   `json:"version"`
+	Entries map[string]cacheEntry `json:"entries"`
+}
+
+//cacheFileVersion is the version of the cache file format.
+const cacheFileVersion = 1
+
+//SaveCache writes all entries of the response cache to a json file at path,
+//replacing it atomically. The file has the format
+//
+//	{"version": 1, "entries": {"<CacheKey>": {"response": <GResponse>,
+//		"etag": "...", "stored": "<RFC 3339 time>"}}}
+//
+//It returns ErrCacheDisabled if the cache is disabled (see Options.CacheTTL).
+func (r *requestProcessor) SaveCache(path string) error {
+	if r.cache == nil {
+		return ErrCacheDisabled
+	}
+	r.cache.mu.Lock()
+	data, err := json.Marshal(cacheFile{Version: cacheFileVersion, Entries: r.cache.entries})
+	r.cache.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+//LoadCache adds the entries of a file written by SaveCache to the response
+//cache, e.g. to warm up the cache after a restart. Entries keep the time
+//they were stored at, so entries older than Options.CacheTTL are stale and
+//revalidated on use. Stale entries without an ETag can't be revalidated
+//and are dropped. An entry only replaces a cached one for the same key if
+//it is newer. It returns ErrCacheDisabled if the cache is disabled.
+func (r *requestProcessor) LoadCache(path string) error {
+	if r.cache == nil {
+		return ErrCacheDisabled
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	file := cacheFile{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	if file.Version != cacheFileVersion {
+		return ErrCacheVersion
+	}
+
+	r.cache.mu.Lock()
+	defer r.cache.mu.Unlock()
+	for key, entry := range file.Entries {
+		if !r.cache.fresh(entry) && entry.ETag == "" {
+			continue
+		}
+		if cached, ok := r.cache.entries[key]; ok && !entry.Stored.After(cached.Stored) {
+			continue
+		}
+		r.cache.entries[key] = entry
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package geopard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheKey(t *testing.T) {
+	params := url.Values{
+		"address":      {"Unter den Linden 1, Berlin"},
+		"language":     {"de"},
+		"key":          {"secret"},
+		"signature":    {"sig"},
+		"channel":      {"web"},
+		"sessiontoken": {"token"},
+	}
+	original := url.Values{}
+	for k, v := range params {
+		original[k] = append([]string(nil), v...)
+	}
+
+	want := "address=Unter+den+Linden+1%2C+Berlin&language=de"
+	if got := CacheKey(params); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(params, original) {
+		t.Errorf("CacheKey modified its argument: %v", params)
+	}
+
+	other := url.Values{}
+	other.Set("language", "de")
+	other.Set("key", "other key")
+	other.Set("address", "Unter den Linden 1, Berlin")
+	if CacheKey(other) != CacheKey(params) {
+		t.Errorf("key depends on parameter order or api key: %q != %q", CacheKey(other), CacheKey(params))
+	}
+}
+
+func TestCacheKeyFromQuery(t *testing.T) {
+	a, _ := url.ParseQuery("key=1&address=x&language=en&components=country%3ADE")
+	b, _ := url.ParseQuery("components=country%3ADE&language=en&address=x&sessiontoken=2")
+	if CacheKey(a) != CacheKey(b) {
+		t.Errorf("%q != %q", CacheKey(a), CacheKey(b))
+	}
+}
+
+func TestCacheRevalidation(t *testing.T) {
+	var requests, notModified atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if inm := req.Header.Get("If-None-Match"); inm != "" {
+			t.Errorf("unexpected If-None-Match %q", inm)
+		}
+		w.Write([]byte(testResponse))
+	}))
+	defer srv.Close()
+
+	r := New(Options{BaseURL: srv.URL + "/?", CacheTTL: time.Hour})
+	defer r.Close()
+	first, err := r.Geocode("Unter den Linden 1, Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//a fresh entry is served without a request
+	if _, err := r.Geocode("Unter den Linden 1, Berlin"); err != nil || requests.Load() != 1 {
+		t.Fatalf("fresh entry: err %v, %d requests", err, requests.Load())
+	}
+
+	//a stale entry is revalidated with its ETag
+	key := ""
+	for k, entry := range r.cache.entries {
+		key = k
+		entry.Stored = entry.Stored.Add(-2 * time.Hour)
+		r.cache.entries[k] = entry
+	}
+	resp, err := r.Geocode("Unter den Linden 1, Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != 2 || notModified.Load() != 1 {
+		t.Fatalf("got %d requests and %d 304s, want 2 and 1", requests.Load(), notModified.Load())
+	}
+	if !reflect.DeepEqual(resp, first) {
+		t.Errorf("304 served %+v, want the cached %+v", resp, first)
+	}
+	entry := r.cache.entries[key]
+	if !r.cache.fresh(entry) || entry.ETag != `"v1"` {
+		t.Errorf("entry not refreshed by the 304: %+v", entry)
+	}
+
+	//the refreshed entry is served from the cache again
+	if _, err := r.Geocode("Unter den Linden 1, Berlin"); err != nil || requests.Load() != 2 {
+		t.Fatalf("refreshed entry: err %v, %d requests", err, requests.Load())
+	}
+}
+
+func TestSaveLoadCache(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		if req.URL.Query().Get("address") == "tagged" {
+			w.Header().Set("ETag", `"v1"`)
+		}
+		w.Write([]byte(testResponse))
+	}))
+	defer srv.Close()
+
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	clock := func() time.Time { return now }
+
+	saved := New(Options{BaseURL: srv.URL + "/?", CacheTTL: time.Hour})
+	defer saved.Close()
+	saved.cache.now = clock
+	for _, address := range []string{"expired", "tagged"} {
+		if _, err := saved.Geocode(address); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = start.Add(90 * time.Minute)
+	if _, err := saved.Geocode("fresh"); err != nil {
+		t.Fatal(err)
+	}
+	path := t.TempDir() + "/cache.json"
+	if err := saved.SaveCache(path); err != nil {
+		t.Fatal(err)
+	}
+
+	//expired entries are dropped unless they can be revalidated
+	now = start.Add(2 * time.Hour)
+	loaded := New(Options{BaseURL: srv.URL + "/?", CacheTTL: time.Hour})
+	defer loaded.Close()
+	loaded.cache.now = clock
+	if err := loaded.LoadCache(path); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]cacheEntry{}
+	for key, entry := range saved.cache.entries {
+		if entry.ETag != "" || entry.Stored.After(start) {
+			want[key] = entry
+		}
+	}
+	if len(want) != 2 || !reflect.DeepEqual(loaded.cache.entries, want) {
+		t.Fatalf("loaded %+v, want %+v", loaded.cache.entries, want)
+	}
+
+	//the fresh entry is served without a request
+	if _, err := loaded.Geocode("fresh"); err != nil || requests.Load() != 3 {
+		t.Errorf("loaded entry: err %v, %d requests", err, requests.Load())
+	}
+}
+
+func TestLoadCacheErrors(t *testing.T) {
+	path := t.TempDir() + "/cache.json"
+	if err := os.WriteFile(path, []byte(`{"version": 2, "entries": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := New(Options{CacheTTL: time.Hour})
+	defer r.Close()
+	if err := r.LoadCache(path); err != ErrCacheVersion {
+		t.Errorf("got %v, want ErrCacheVersion", err)
+	}
+
+	disabled := New(Options{})
+	defer disabled.Close()
+	if err := disabled.LoadCache(path); err != ErrCacheDisabled {
+		t.Errorf("load: got %v, want ErrCacheDisabled", err)
+	}
+	if err := disabled.SaveCache(path); err != ErrCacheDisabled {
+		t.Errorf("save: got %v, want ErrCacheDisabled", err)
+	}
+}
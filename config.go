@@ -0,0 +1,40 @@
+package geopard
+
+import "time"
+
+//Config describes the effective settings of a request processor after
+//defaults have been applied. It never contains the api key.
+type Config struct {
+	Lang             string
+	MaxQueriesPerSec int
+	HasAPIKey        bool
+	CacheEnabled     bool
+	CacheTTL         time.Duration
+	BaseURL          string
+	MaxRetries       int
+	MaxResults       int
+}
+
+//Config returns the effective, non-secret settings of the request processor.
+func (r *requestProcessor) Config() Config {
+	cfg := Config{
+		Lang:             r.lang,
+		MaxQueriesPerSec: r.maxQueriesPerSec,
+		HasAPIKey:        r.apiKey != "",
+		BaseURL:          r.baseURL,
+		MaxRetries:       r.maxRetries,
+		MaxResults:       r.maxResults,
+	}
+	if r.cache != nil {
+		cfg.CacheEnabled = true
+		cfg.CacheTTL = r.cache.ttl
+	}
+	return cfg
+}
+
+//MaxQueriesPerSec returns the configured rate limit in requests per second,
+//after applying the default. In adaptive mode the rate currently allowed
+//may be lower, see Stats.EffectiveRate.
+func (r *requestProcessor) MaxQueriesPerSec() int {
+	return r.maxQueriesPerSec
+}
@@ -0,0 +1,186 @@
+package geopard
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//CSVOptions configure GeocodeCSV.
+type CSVOptions struct {
+	//AddressColumn is the zero based index of the column holding the address.
+	AddressColumn int
+	//Header marks the first row as header. It is copied to the output,
+	//extended by the names of the added columns, unless a run is resumed.
+	Header bool
+	//ChunkSize is the number of rows geocoded as one batch (default 100).
+	//The checkpoint is written after every chunk.
+	ChunkSize int
+	//Checkpoint is the path of the checkpoint file. If it is empty no
+	//checkpoint is read or written.
+	Checkpoint string
+}
+
+//csvColumns are the names of the columns appended to every row.
+var csvColumns = []string{"lat", "lng", "formatted_address", "place_id", "error"}
+
+//csvChunkSize is the default for CSVOptions.ChunkSize.
+const csvChunkSize = 100
+
+//GeocodeCSV reads rows from in, geocodes the address column of every row
+//and writes the rows to out, extended by the location, formatted address
+//and place id of the first result and the error, if any. Rows are geocoded
+//in chunks of CSVOptions.ChunkSize using GeocodeBatch and written in input
+//order.
+//
+//If CSVOptions.Checkpoint is set, the number of data rows (not counting
+//the header) written so far is stored in that file after every chunk, as
+//a decimal number followed by a newline. The file is replaced atomically.
+//A later run with the same checkpoint skips that many rows of in, so out
+//should be opened for appending when resuming; a checkpoint covering all
+//rows makes it return nil without writing anything. When ctx is done or
+//the daily quota (see Options.EnforceDailyQuota) is exhausted, the rows
+//finished so far are written together with the checkpoint and the error
+//is returned; the interrupted rows are geocoded again on the next run.
+//Rows failing for other reasons, including timeouts set by WithTimeout,
+//are written with their error.
+func (r *requestProcessor) GeocodeCSV(ctx context.Context, in io.Reader, out io.Writer, opts CSVOptions, reqOpts ...RequestOption) error {
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = csvChunkSize
+	}
+	done, err := readCheckpoint(opts.Checkpoint)
+	if err != nil {
+		return err
+	}
+
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+	writer := csv.NewWriter(out)
+
+	if opts.Header {
+		header, err := reader.Read()
+		if err != nil {
+			return err
+		}
+		if done == 0 {
+			writer.Write(append(header, csvColumns...))
+		}
+	}
+	for skipped := 0; skipped < done; skipped++ {
+		_, err := reader.Read()
+		if err == io.EOF {
+			//the checkpoint covers all rows, the job is done
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		rows := make([][]string, 0, chunkSize)
+		for len(rows) < chunkSize {
+			row, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			rows = append(rows, row)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		addresses := make([]string, len(rows))
+		for i, row := range rows {
+			if opts.AddressColumn < len(row) {
+				addresses[i] = row[opts.AddressColumn]
+			}
+		}
+
+		var stopErr error
+		for i, item := range r.GeocodeBatch(ctx, addresses, reqOpts...) {
+			if interrupted(ctx, item.Err) {
+				stopErr = item.Err
+				break
+			}
+			writer.Write(append(rows[i], csvResult(item)...))
+			done++
+		}
+
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+		if err := writeCheckpoint(opts.Checkpoint, done); err != nil {
+			return err
+		}
+		if stopErr != nil {
+			return stopErr
+		}
+	}
+}
+
+//interrupted reports whether a batch item failed because the run was
+//stopped rather than because of its address. Only the run's ctx stops it;
+//an item timing out under a per call WithTimeout is an error row.
+func interrupted(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	return ctx.Err() != nil || errors.Is(err, ErrQuotaExceeded)
+}
+
+//csvResult returns the columns appended to a row for the given item.
+func csvResult(item BatchItem) []string {
+	if item.Err != nil {
+		return []string{"", "", "", "", item.Err.Error()}
+	}
+	if len(item.Response.Results) == 0 {
+		return []string{"", "", "", "", ""}
+	}
+	res := item.Response.Results[0]
+	loc := res.Geometry.Location
+	return []string{
+		strconv.FormatFloat(loc.Lat, 'f', -1, 64),
+		strconv.FormatFloat(loc.Lng, 'f', -1, 64),
+		res.FormattedAddr,
+		res.PlaceId.String(),
+		"",
+	}
+}
+
+//readCheckpoint returns the number of rows recorded in the checkpoint file
+//or 0 if there is none.
+func readCheckpoint(path string) (int, error) {
+	if path == "" {
+		return 0, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+//writeCheckpoint atomically replaces the checkpoint file.
+func writeCheckpoint(path string, done int) error {
+	if path == "" {
+		return nil
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(done)+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
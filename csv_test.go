@@ -0,0 +1,132 @@
+package geopard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//csvServer answers every address with a result named after it. The
+//address "slow" is answered after 300ms.
+func csvServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		address := req.URL.Query().Get("address")
+		if address == "slow" {
+			select {
+			case <-time.After(300 * time.Millisecond):
+			case <-req.Context().Done():
+				return
+			}
+		}
+		fmt.Fprintf(w, `{"status":"OK","results":[{"formatted_address":%q,"place_id":%q,"geometry":{"location":{"lat":1,"lng":2}}}]}`, address, address)
+	}))
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestGeocodeCSVResume(t *testing.T) {
+	srv := csvServer()
+	defer srv.Close()
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+	in := "address\na\nb\nc\n"
+	opts := CSVOptions{Header: true, ChunkSize: 1, Checkpoint: checkpoint}
+
+	//the quota runs out after two rows
+	r := New(Options{BaseURL: srv.URL + "/?", DailyQuota: 2, EnforceDailyQuota: true})
+	var out strings.Builder
+	err := r.GeocodeCSV(context.Background(), strings.NewReader(in), &out, opts)
+	r.Close()
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("got %v, want ErrQuotaExceeded", err)
+	}
+	if got := readFile(t, checkpoint); got != "2\n" {
+		t.Errorf("checkpoint %q, want 2", got)
+	}
+
+	//the rerun continues with the third row
+	r = New(Options{BaseURL: srv.URL + "/?"})
+	defer r.Close()
+	if err := r.GeocodeCSV(context.Background(), strings.NewReader(in), &out, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := "address,lat,lng,formatted_address,place_id,error\n" +
+		"a,1,2,a,a,\n" +
+		"b,1,2,b,b,\n" +
+		"c,1,2,c,c,\n"
+	if out.String() != want {
+		t.Errorf("got output\n%s\nwant\n%s", out.String(), want)
+	}
+	if got := readFile(t, checkpoint); got != "3\n" {
+		t.Errorf("checkpoint %q, want 3", got)
+	}
+
+	//a checkpoint covering all rows means the job is done
+	out.Reset()
+	if err := r.GeocodeCSV(context.Background(), strings.NewReader(in), &out, opts); err != nil {
+		t.Errorf("got %v for a finished job", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("finished job wrote %q", out.String())
+	}
+}
+
+func TestGeocodeCSVCanceled(t *testing.T) {
+	srv := csvServer()
+	defer srv.Close()
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+	r := New(Options{BaseURL: srv.URL + "/?"})
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var out strings.Builder
+	err := r.GeocodeCSV(ctx, strings.NewReader("a\nslow\nb\n"), &out, CSVOptions{ChunkSize: 1, Checkpoint: checkpoint})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if got := readFile(t, checkpoint); got != "1\n" {
+		t.Errorf("checkpoint %q, want 1", got)
+	}
+	if want := "a,1,2,a,a,\n"; out.String() != want {
+		t.Errorf("got output %q, want %q", out.String(), want)
+	}
+}
+
+func TestGeocodeCSVRowTimeout(t *testing.T) {
+	srv := csvServer()
+	defer srv.Close()
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+	r := New(Options{BaseURL: srv.URL + "/?"})
+	defer r.Close()
+
+	var out strings.Builder
+	err := r.GeocodeCSV(context.Background(), strings.NewReader("a\nslow\nb\n"), &out,
+		CSVOptions{ChunkSize: 1, Checkpoint: checkpoint}, WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("a row timeout stopped the job: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 || lines[0] != "a,1,2,a,a," || lines[2] != "b,1,2,b,b," {
+		t.Fatalf("unexpected output\n%s", out.String())
+	}
+	if !strings.HasPrefix(lines[1], "slow,,,,,timeout") {
+		t.Errorf("slow row %q, want an error row", lines[1])
+	}
+	if got := readFile(t, checkpoint); got != "3\n" {
+		t.Errorf("checkpoint %q, want 3", got)
+	}
+}
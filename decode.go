@@ -0,0 +1,185 @@
+package geopard
+
+import (
+	"reflect"
+	"sync"
+)
+
+//Fields is a set of result parts to decode, see Options.Fields.
+type Fields uint
+
+const (
+	//FieldLocation is the location and location type of the geometry.
+	FieldLocation Fields = 1 << iota
+	//FieldFormattedAddress is the formatted address.
+	FieldFormattedAddress
+	//FieldComponents are the address components and postcode localities.
+	FieldComponents
+	//FieldGeometry is the whole geometry including viewport and bounds.
+	//It implies FieldLocation.
+	FieldGeometry
+
+	//FieldAll selects all fields.
+	FieldAll = FieldLocation | FieldFormattedAddress | FieldComponents | FieldGeometry
+)
+
+//LeanResult is the trimmed down result decoded if Options.Lean is set.
+//It lacks viewport, bounds and address components of GResult.
+type LeanResult struct {
+	PlaceId       PlaceID      `json:"place_id"`
+	FormattedAddr string       `json:"formatted_address"`
+	Geometry      LeanGeometry `json:"geometry"`
+	PartialMatch  bool         `json:"partial_match"`
+	Types         []string     `json:"types"`
+}
+
+//LeanGeometry is the trimmed down geometry of a LeanResult.
+type LeanGeometry struct {
+	Location     GPoint `json:"location"`
+	LocationType string `json:"location_type"`
+}
+
+type leanResponse struct {
+	Status  string       `json:"status"`
+	Results []LeanResult `json:"results"`
+}
+
+//GResult converts the lean result to a GResult with only the lean fields set.
+func (l LeanResult) GResult() GResult {
+	return GResult{
+		PlaceId:       l.PlaceId,
+		FormattedAddr: l.FormattedAddr,
+		Geometry: GGeometry{
+			Location:     l.Geometry.Location,
+			LocationType: l.Geometry.LocationType,
+		},
+		PartialMatch: l.PartialMatch,
+		Types:        l.Types,
+	}
+}
+
+//Lean returns the subset of the result that is decoded in lean mode.
+func (r GResult) Lean() LeanResult {
+	return LeanResult{
+		PlaceId:       r.PlaceId,
+		FormattedAddr: r.FormattedAddr,
+		Geometry: LeanGeometry{
+			Location:     r.Geometry.Location,
+			LocationType: r.Geometry.LocationType,
+		},
+		PartialMatch: r.PartialMatch,
+		Types:        r.Types,
+	}
+}
+
+//projections caches the response struct types built by projectionType.
+var projections sync.Map
+
+var (
+	resultType       = reflect.TypeOf(GResult{})
+	leanGeometryType = reflect.TypeOf(LeanGeometry{})
+
+	//indices of the GGeometry fields set from a LeanGeometry
+	geometryLocation     = fieldIndex(reflect.TypeOf(GGeometry{}), "Location")
+	geometryLocationType = fieldIndex(reflect.TypeOf(GGeometry{}), "LocationType")
+)
+
+func fieldIndex(t reflect.Type, name string) []int {
+	sf, _ := t.FieldByName(name)
+	return sf.Index
+}
+
+//projectionType returns a response struct type whose results only have
+//the GResult fields selected by f, so the json decoder skips the others.
+//Unless FieldGeometry is set the geometry is decoded as LeanGeometry.
+func projectionType(f Fields) reflect.Type {
+	if t, ok := projections.Load(f); ok {
+		return t.(reflect.Type)
+	}
+
+	fields := make([]reflect.StructField, 0, resultType.NumField())
+	for i := 0; i < resultType.NumField(); i++ {
+		sf := resultType.Field(i)
+		switch {
+		case !sf.IsExported():
+			continue
+		case sf.Name == "FormattedAddr" && f&FieldFormattedAddress == 0:
+			continue
+		case (sf.Name == "AddrComponents" || sf.Name == "PostcodeLocalities") && f&FieldComponents == 0:
+			continue
+		case sf.Name == "Geometry" && f&FieldGeometry == 0:
+			if f&FieldLocation == 0 {
+				continue
+			}
+			sf.Type = leanGeometryType
+		}
+		fields = append(fields, reflect.StructField{Name: sf.Name, Type: sf.Type, Tag: sf.Tag})
+	}
+
+	t := reflect.StructOf([]reflect.StructField{
+		{Name: "Status", Type: reflect.TypeOf(""), Tag: `json:"status"`},
+		{Name: "Results", Type: reflect.SliceOf(reflect.StructOf(fields)), Tag: `json:"results"`},
+	})
+	projections.Store(f, t)
+	return t
+}
+
+//decodeProjection decodes a response into the projection type for the
+//configured fields and copies the decoded fields to response.
+func (r *requestProcessor) decodeProjection(body []byte, response *GResponse) error {
+	projected := reflect.New(projectionType(r.fields))
+	if err := r.unmarshal(body, projected.Interface()); err != nil {
+		return err
+	}
+
+	response.Status = projected.Elem().Field(0).String()
+	results := projected.Elem().Field(1)
+	//resolve the GResult field of every projected field once per
+	//response instead of once per result
+	projection := results.Type().Elem()
+	index := make([]int, projection.NumField())
+	for j := range index {
+		sf, _ := resultType.FieldByName(projection.Field(j).Name)
+		index[j] = sf.Index[0]
+	}
+
+	response.Results = make([]GResult, results.Len())
+	for i := range response.Results {
+		src := results.Index(i)
+		dst := reflect.ValueOf(&response.Results[i]).Elem()
+		for j, k := range index {
+			field := src.Field(j)
+			if field.Type() == leanGeometryType {
+				//copy location and location type into the full geometry
+				geometry := dst.Field(k)
+				geometry.FieldByIndex(geometryLocation).Set(field.Field(0))
+				geometry.FieldByIndex(geometryLocationType).Set(field.Field(1))
+				continue
+			}
+			dst.Field(k).Set(field)
+		}
+	}
+	return nil
+}
+
+//decode parses a response body, skipping the heavy parts in lean mode or
+//the fields not selected by Options.Fields.
+func (r *requestProcessor) decode(body []byte, response *GResponse) error {
+	if !r.lean {
+		if r.fields != 0 && r.fields&FieldAll != FieldAll {
+			return r.decodeProjection(body, response)
+		}
+		return r.unmarshal(body, response)
+	}
+
+	lean := leanResponse{}
+	if err := r.unmarshal(body, &lean); err != nil {
+		return err
+	}
+	response.Status = lean.Status
+	response.Results = make([]GResult, len(lean.Results))
+	for i, l := range lean.Results {
+		response.Results[i] = l.GResult()
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package geopard
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"testing"
+)
+
+//benchResponse returns the body of a response with n results carrying all
+//parts of a typical street address result.
+func benchResponse(n int) []byte {
+	area := GArea{NorthEast: GPoint{52.52, 13.41}, SouthWest: GPoint{52.51, 13.39}}
+	response := GResponse{Status: "OK"}
+	for i := 0; i < n; i++ {
+		response.Results = append(response.Results, GResult{
+			PlaceId:       PlaceID("ChIJ" + strconv.Itoa(i)),
+			FormattedAddr: "Unter den Linden " + strconv.Itoa(i) + ", 10117 Berlin, Germany",
+			Geometry: GGeometry{
+				Location:     GPoint{52.517, 13.397},
+				Viewport:     area,
+				Bounds:       area,
+				LocationType: LocationRooftop,
+			},
+			AddrComponents: []GAddrComponent{
+				{strconv.Itoa(i), strconv.Itoa(i), []string{"street_number"}},
+				{"Unter den Linden", "Unter den Linden", []string{"route"}},
+				{"Mitte", "Mitte", []string{"political", "sublocality", "sublocality_level_1"}},
+				{"Berlin", "Berlin", []string{"locality", "political"}},
+				{"Kreisfreie Stadt Berlin", "Kreisfreie Stadt Berlin", []string{"administrative_area_level_3", "political"}},
+				{"Berlin", "BE", []string{"administrative_area_level_1", "political"}},
+				{"Germany", "DE", []string{"country", "political"}},
+				{"10117", "10117", []string{"postal_code"}},
+			},
+			Types: []string{"street_address"},
+		})
+	}
+	body, err := json.Marshal(response)
+	if err != nil {
+		panic(err)
+	}
+	return body
+}
+
+func TestDecodeProjection(t *testing.T) {
+	body := benchResponse(2)
+	var full GResponse
+	if err := json.Unmarshal(body, &full); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New(Options{Fields: FieldLocation | FieldComponents})
+	defer r.Close()
+	var response GResponse
+	if err := r.decode(body, &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != "OK" || len(response.Results) != 2 {
+		t.Fatalf("unexpected response %+v", response)
+	}
+	for i, res := range response.Results {
+		want := full.Results[i]
+		if res.PlaceId != want.PlaceId || res.Geometry.Location != want.Geometry.Location ||
+			res.Geometry.LocationType != want.Geometry.LocationType || len(res.AddrComponents) != len(want.AddrComponents) {
+			t.Errorf("result %d: got %+v, want the selected fields of %+v", i, res, want)
+		}
+		if res.FormattedAddr != "" || res.Geometry.Viewport != (GArea{}) {
+			t.Errorf("result %d: unselected fields decoded: %+v", i, res)
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	body := benchResponse(10)
+	r := New(Options{})
+	defer r.Close()
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(body)))
+		for i := 0; i < b.N; i++ {
+			buf := getBuffer()
+			buf.ReadFrom(bytes.NewReader(body))
+			var response GResponse
+			if err := r.decode(buf.Bytes(), &response); err != nil {
+				b.Fatal(err)
+			}
+			putBuffer(buf)
+		}
+	})
+	b.Run("readall", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(body)))
+		for i := 0; i < b.N; i++ {
+			data, _ := io.ReadAll(bytes.NewReader(body))
+			var response GResponse
+			if err := r.decode(data, &response); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+//benchmarkDecode measures decoding body with the given options.
+func benchmarkDecode(b *testing.B, body []byte, opts Options) {
+	r := New(opts)
+	defer r.Close()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	for i := 0; i < b.N; i++ {
+		var response GResponse
+		if err := r.decode(body, &response); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeLean(b *testing.B) {
+	body := benchResponse(100)
+	b.Run("full", func(b *testing.B) { benchmarkDecode(b, body, Options{}) })
+	b.Run("lean", func(b *testing.B) { benchmarkDecode(b, body, Options{Lean: true}) })
+}
+
+func BenchmarkDecodeFields(b *testing.B) {
+	body := benchResponse(100)
+	b.Run("unmarshal", func(b *testing.B) { benchmarkDecode(b, body, Options{}) })
+	b.Run("location", func(b *testing.B) { benchmarkDecode(b, body, Options{Fields: FieldLocation}) })
+	b.Run("address", func(b *testing.B) {
+		benchmarkDecode(b, body, Options{Fields: FieldLocation | FieldFormattedAddress})
+	})
+	b.Run("components", func(b *testing.B) {
+		benchmarkDecode(b, body, Options{Fields: FieldLocation | FieldComponents})
+	})
+}
@@ -0,0 +1,17 @@
+package geopard
+
+import (
+	"net/url"
+	"strings"
+)
+
+//isGoogleURL reports whether rawURL points to one of Google's hosts.
+func isGoogleURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return host == "maps.googleapis.com" || host == "maps.google.cn" ||
+		strings.HasSuffix(host, ".googleapis.com") || strings.HasSuffix(host, ".google.com")
+}
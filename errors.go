@@ -0,0 +1,144 @@
+package geopard
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+//maxDecodeErrorBody is the number of body bytes kept in a DecodeError.
+const maxDecodeErrorBody = 256
+
+//ErrDecode matches every DecodeError via errors.Is.
+var ErrDecode = errors.New("malformed response")
+
+//DecodeError is returned if a response body is no valid geocoding json,
+//e.g. because a proxy answered with an html error page.
+type DecodeError struct {
+	//URL is the request url with the api key removed.
+	URL string
+	//Body holds the beginning of the response body.
+	Body string
+	//Err is the error returned by the json decoder.
+	Err error
+}
+
+func newDecodeError(url string, body []byte, err error) *DecodeError {
+	if len(body) > maxDecodeErrorBody {
+		body = body[:maxDecodeErrorBody]
+	}
+	return &DecodeError{URL: sanitizeURL(url), Body: string(body), Err: err}
+}
+
+func (e *DecodeError) Error() string {
+	return "malformed response from " + e.URL + ": " + e.Err.Error() + ": body " + strconv.Quote(e.Body)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrDecode
+}
+
+//ErrTimeout matches, via errors.Is, the errors returned if the context
+//deadline expired during a request, including while the response was
+//received or decoded, which otherwise fails with a read or decode error.
+//These errors unwrap to the context's error, so they match
+//context.DeadlineExceeded too.
+var ErrTimeout = errors.New("timeout")
+
+type timeoutError struct {
+	err error
+}
+
+func (e *timeoutError) Error() string {
+	return "timeout: " + e.err.Error()
+}
+
+func (e *timeoutError) Unwrap() error {
+	return e.err
+}
+
+func (e *timeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+//deadlineError returns a timeoutError if the deadline of ctx expired and
+//err otherwise.
+func deadlineError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &timeoutError{err: ctx.Err()}
+	}
+	return err
+}
+
+//ErrorClass categorizes errors returned by the request processor by how
+//a caller should react to them, e.g. when deciding whether to retry.
+type ErrorClass int
+
+const (
+	//NoError is the class of a nil error.
+	NoError ErrorClass = iota
+	//Transient errors are expected to go away when retrying later, e.g.
+	//network failures, malformed responses, timeouts (ErrTimeout,
+	//ErrAttemptTimeout) or UNKNOWN_ERROR from Google.
+	Transient
+	//RateLimited errors signal that the quota was exceeded (OVER_QUERY_LIMIT)
+	//or that too many requests are pending (ErrQueueFull). Retrying makes
+	//sense after backing off.
+	RateLimited
+	//Permanent errors will not change by retrying the same request, e.g.
+	//REQUEST_DENIED, INVALID_REQUEST, rejected partial matches or a
+	//canceled context.
+	Permanent
+	//ZeroResults means the request succeeded but nothing was found.
+	ZeroResults
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case NoError:
+		return "none"
+	case Transient:
+		return "transient"
+	case RateLimited:
+		return "rate limited"
+	case Permanent:
+		return "permanent"
+	case ZeroResults:
+		return "zero results"
+	}
+	return "unknown"
+}
+
+//Classify returns the ErrorClass of an error returned by the request
+//processor. Errors that are neither caused by the geocoding service nor
+//by the context are considered to originate from the network and are
+//classified as Transient.
+func Classify(err error) ErrorClass {
+	switch {
+	case err == nil:
+		return NoError
+	case errors.Is(err, ErrZeroResults), errors.Is(err, ErrEmptyResults):
+		return ZeroResults
+	case errors.Is(err, ErrOverLimit), errors.Is(err, ErrQueueFull):
+		return RateLimited
+	case errors.Is(err, ErrRequestDenied),
+		errors.Is(err, ErrInvalidRequest),
+		errors.Is(err, ErrPartialMatch),
+		errors.Is(err, ErrReplayMissing),
+		errors.Is(err, ErrURLTooLong),
+		errors.Is(err, ErrUnknownBucket),
+		errors.Is(err, ErrAmbiguous),
+		errors.Is(err, ErrQuotaExceeded),
+		errors.Is(err, ErrPOSTNotAllowed),
+		errors.Is(err, ErrConflictingParams),
+		errors.Is(err, ErrCoordFormat),
+		errors.Is(err, ErrEmptyAddress),
+		errors.Is(err, context.Canceled):
+		return Permanent
+	}
+	return Transient
+}
@@ -0,0 +1,92 @@
+package geopard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+//slowServer sends the first half of a response and the rest after delay.
+func slowServer(delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		half := len(testResponse) / 2
+		w.Write([]byte(testResponse[:half]))
+		w.(http.Flusher).Flush()
+		select {
+		case <-time.After(delay):
+			w.Write([]byte(testResponse[half:]))
+		case <-req.Context().Done():
+		}
+	}))
+}
+
+func TestDeadlineWhileReadingBody(t *testing.T) {
+	srv := slowServer(time.Second)
+	defer srv.Close()
+	r := New(Options{BaseURL: srv.URL + "/?"})
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := r.GeocodeContext(ctx, "Unter den Linden 1, Berlin")
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("got %v, want ErrTimeout", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+	if Classify(err) != Transient {
+		t.Errorf("got class %v, want Transient", Classify(err))
+	}
+}
+
+func TestPerAttemptTimeout(t *testing.T) {
+	srv := slowServer(time.Second)
+	defer srv.Close()
+	r := New(Options{BaseURL: srv.URL + "/?", PerAttemptTimeout: 50 * time.Millisecond})
+	defer r.Close()
+
+	_, err := r.GeocodeContext(context.Background(), "Unter den Linden 1, Berlin")
+	if !errors.Is(err, ErrAttemptTimeout) {
+		t.Errorf("got %v, want ErrAttemptTimeout", err)
+	}
+	if errors.Is(err, ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("attempt timeout %v reported as deadline of the call", err)
+	}
+	if Classify(err) != Transient {
+		t.Errorf("got class %v, want Transient", Classify(err))
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{nil, NoError},
+		{ErrZeroResults, ZeroResults},
+		{ErrEmptyResults, ZeroResults},
+		{ErrOverLimit, RateLimited},
+		{ErrQueueFull, RateLimited},
+		{ErrRequestDenied, Permanent},
+		{ErrInvalidRequest, Permanent},
+		{ErrConflictingParams, Permanent},
+		{ErrEmptyAddress, Permanent},
+		{context.Canceled, Permanent},
+		{fmt.Errorf("get: %w", context.Canceled), Permanent},
+		{context.DeadlineExceeded, Transient},
+		{&timeoutError{err: context.DeadlineExceeded}, Transient},
+		{ErrAttemptTimeout, Transient},
+		{ErrUnknown, Transient},
+		{errors.New("connection reset"), Transient},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package geopard
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/url"
+	"strconv"
+)
+
+//The following structs describe the subset of GeoJSON (RFC 7946)
+//produced by GResponse.GeoJSON.
+type (
+	geoJSONCollection struct {
+		Type     string           `json:"type"`
+		CRS      geoJSONCRS       `json:"crs"`
+		Features []geoJSONFeature `json:"features"`
+	}
+	geoJSONCRS struct {
+		Type       string `json:"type"`
+		Properties struct {
+			Name string `json:"name"`
+		} `json:"properties"`
+	}
+	geoJSONFeature struct {
+		Type       string            `json:"type"`
+		Geometry   geoJSONPoint      `json:"geometry"`
+		Properties geoJSONProperties `json:"properties"`
+	}
+	geoJSONPoint struct {
+		Type        string     `json:"type"`
+		Coordinates [2]float64 `json:"coordinates"`
+	}
+	geoJSONProperties struct {
+		FormattedAddr string   `json:"formatted_address"`
+		PlaceId       PlaceID  `json:"place_id"`
+		Types         []string `json:"types"`
+	}
+)
+
+//GeoJSON encodes the response as a GeoJSON FeatureCollection. Every result
+//becomes a Feature with a Point geometry at its location and the formatted
+//address, place id and types as properties. As required by the GeoJSON
+//spec the coordinates are ordered [lng, lat]. The datum is stated in a crs
+//member naming OGC CRS84, which is WGS 84 (see SRID) in lng, lat order, for
+//consumers that don't assume it.
+func (r GResponse) GeoJSON() ([]byte, error) {
+	collection := geoJSONCollection{
+		Type:     "FeatureCollection",
+		Features: make([]geoJSONFeature, len(r.Results)),
+	}
+	collection.CRS.Type = "name"
+	collection.CRS.Properties.Name = "urn:ogc:def:crs:OGC:1.3:CRS84"
+	for i, res := range r.Results {
+		loc := res.Geometry.Location
+		collection.Features[i] = geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONPoint{
+				Type:        "Point",
+				Coordinates: [2]float64{loc.Lng, loc.Lat},
+			},
+			Properties: geoJSONProperties{
+				FormattedAddr: res.FormattedAddr,
+				PlaceId:       res.PlaceId,
+				Types:         res.Types,
+			},
+		}
+	}
+	return json.Marshal(collection)
+}
+
+//MapsURL returns a Google Maps link showing the result, like
+//https://www.google.com/maps/search/?api=1&query=<lat>,<lng>&query_place_id=<id>.
+//The place id is left out if the result has none.
+func (r GResult) MapsURL() string {
+	loc := r.Geometry.Location
+	q := url.Values{}
+	q.Set("api", "1")
+	q.Set("query", strconv.FormatFloat(loc.Lat, 'f', -1, 64)+","+strconv.FormatFloat(loc.Lng, 'f', -1, 64))
+	if r.PlaceId != "" {
+		q.Set("query_place_id", r.PlaceId.String())
+	}
+	return "https://www.google.com/maps/search/?" + q.Encode()
+}
+
+//The following structs describe the subset of KML produced by GResponse.KML.
+type (
+	kmlDocument struct {
+		XMLName    xml.Name       `xml:"http://www.opengis.net/kml/2.2 kml"`
+		Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+	}
+	kmlPlacemark struct {
+		Name  string `xml:"name"`
+		Point struct {
+			Coordinates string `xml:"coordinates"`
+		} `xml:"Point"`
+	}
+)
+
+//KML encodes the response as a KML 2.2 document, e.g. for Google Earth.
+//Every result becomes a Placemark named by its formatted address with a
+//Point at its location. As required by KML the coordinates are given as
+//lng,lat,altitude with an altitude of 0.
+func (r GResponse) KML() ([]byte, error) {
+	doc := kmlDocument{Placemarks: make([]kmlPlacemark, len(r.Results))}
+	for i, res := range r.Results {
+		loc := res.Geometry.Location
+		doc.Placemarks[i].Name = res.FormattedAddr
+		doc.Placemarks[i].Point.Coordinates = strconv.FormatFloat(loc.Lng, 'f', -1, 64) + "," +
+			strconv.FormatFloat(loc.Lat, 'f', -1, 64) + ",0"
+	}
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
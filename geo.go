@@ -0,0 +1,155 @@
+package geopard
+
+import "math"
+
+//earthRadius is the mean radius of the earth in meters.
+const earthRadius = 6371008.8
+
+//SRID is the spatial reference id of all coordinates returned by Google:
+//EPSG:4326, latitude and longitude on the WGS 84 datum.
+const SRID = 4326
+
+//WithSRID returns the point tagged with its SRID, with the coordinates in
+//x, y order as expected by GIS tools, e.g. for PostGIS'
+//ST_SetSRID(ST_MakePoint(lng, lat), srid).
+func (p GPoint) WithSRID() (srid int, lng, lat float64) {
+	return SRID, p.Lng, p.Lat
+}
+
+//BoundingBox returns the area that extends radius meters from center to
+//the north, east, south and west. Latitudes are clamped to the poles and
+//longitudes wrapped into [-180, 180], so a box reaching across the 180th
+//meridian crosses the antimeridian (see CrossesAntimeridian). A box that
+//reaches a pole or is wider than the whole globe spans all longitudes.
+func BoundingBox(center GPoint, radius float64) GArea {
+	dLat := degrees(radius / earthRadius)
+	north, south := center.Lat+dLat, center.Lat-dLat
+	if north >= 90 || south <= -90 {
+		//close to the poles the meridians converge and cos(lat) drops to 0
+		return GArea{
+			NorthEast: GPoint{Lat: math.Min(north, 90), Lng: 180},
+			SouthWest: GPoint{Lat: math.Max(south, -90), Lng: -180},
+		}
+	}
+	dLng := dLat / math.Cos(radians(center.Lat))
+	if dLng >= 180 {
+		return GArea{
+			NorthEast: GPoint{Lat: north, Lng: 180},
+			SouthWest: GPoint{Lat: south, Lng: -180},
+		}
+	}
+	return GArea{
+		NorthEast: GPoint{Lat: north, Lng: wrapLng(center.Lng + dLng)},
+		SouthWest: GPoint{Lat: south, Lng: wrapLng(center.Lng - dLng)},
+	}
+}
+
+//wrapLng maps a longitude into [-180, 180].
+func wrapLng(lng float64) float64 {
+	if lng >= -180 && lng <= 180 {
+		return lng
+	}
+	lng = math.Mod(lng+180, 360)
+	if lng < 0 {
+		lng += 360
+	}
+	return lng - 180
+}
+
+//CrossesAntimeridian reports whether the area extends across the 180th
+//meridian, in which case its north east corner lies west of its south west
+//corner.
+func (a GArea) CrossesAntimeridian() bool {
+	return a.NorthEast.Lng < a.SouthWest.Lng
+}
+
+//Contains reports whether p lies inside the area or on its border. Areas
+//crossing the antimeridian are treated as the union of their parts east
+//and west of it.
+func (a GArea) Contains(p GPoint) bool {
+	if p.Lat < a.SouthWest.Lat || p.Lat > a.NorthEast.Lat {
+		return false
+	}
+	if a.CrossesAntimeridian() {
+		return p.Lng >= a.SouthWest.Lng || p.Lng <= a.NorthEast.Lng
+	}
+	return p.Lng >= a.SouthWest.Lng && p.Lng <= a.NorthEast.Lng
+}
+
+//Center returns the point in the middle of the area. For areas crossing
+//the antimeridian the longitude is normalized to [-180, 180].
+func (a GArea) Center() GPoint {
+	lng := (a.SouthWest.Lng + a.NorthEast.Lng) / 2
+	if a.CrossesAntimeridian() {
+		lng += 180
+		if lng > 180 {
+			lng -= 360
+		}
+	}
+	return GPoint{Lat: (a.SouthWest.Lat + a.NorthEast.Lat) / 2, Lng: lng}
+}
+
+//inPolygon reports whether p lies inside the polygon using the ray casting
+//algorithm. Coordinates are treated as planar with lng as x and lat as y,
+//which is accurate enough for polygons that don't span huge distances.
+//The polygon may be closed (last point equals first) or open.
+func (p GPoint) inPolygon(poly []GPoint) bool {
+	inside := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lng < (b.Lng-a.Lng)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lng {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+//DistanceTo returns the great-circle distance in meters between p and other
+//using the haversine formula on a spherical earth.
+func (p GPoint) DistanceTo(other GPoint) float64 {
+	lat1, lat2 := radians(p.Lat), radians(other.Lat)
+	dLat := lat2 - lat1
+	dLng := radians(other.Lng - p.Lng)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+//BearingTo returns the initial great-circle bearing from p to other in
+//degrees clockwise from north, in the range [0, 360).
+func (p GPoint) BearingTo(other GPoint) float64 {
+	lat1, lat2 := radians(p.Lat), radians(other.Lat)
+	dLng := radians(other.Lng - p.Lng)
+
+	y := math.Sin(dLng) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	bearing := math.Mod(degrees(math.Atan2(y, x))+360, 360)
+	if bearing == 360 {
+		bearing = 0
+	}
+	return bearing
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+//round rounds all coordinates of the geometry to the given number of
+//decimals.
+func (g *GGeometry) round(decimals int) {
+	scale := math.Pow(10, float64(decimals))
+	for _, p := range []*GPoint{
+		&g.Location,
+		&g.Viewport.NorthEast, &g.Viewport.SouthWest,
+		&g.Bounds.NorthEast, &g.Bounds.SouthWest,
+	} {
+		p.Lat = math.Round(p.Lat*scale) / scale
+		p.Lng = math.Round(p.Lng*scale) / scale
+	}
+}
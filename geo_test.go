@@ -0,0 +1,120 @@
+package geopard
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBoundingBox(t *testing.T) {
+	tests := []struct {
+		name   string
+		center GPoint
+		radius float64
+		want   GArea
+	}{
+		{"antimeridian east", GPoint{0, 179.99}, 5000,
+			GArea{NorthEast: GPoint{0.04497, -179.96503}, SouthWest: GPoint{-0.04497, 179.94503}}},
+		{"antimeridian west", GPoint{0, -179.99}, 5000,
+			GArea{NorthEast: GPoint{0.04497, -179.94503}, SouthWest: GPoint{-0.04497, 179.96503}}},
+		{"north pole", GPoint{89.99, 10}, 5000,
+			GArea{NorthEast: GPoint{90, 180}, SouthWest: GPoint{89.94503, -180}}},
+		{"south pole", GPoint{-90, 0}, 5000,
+			GArea{NorthEast: GPoint{-89.95503, 180}, SouthWest: GPoint{-90, -180}}},
+		{"larger than the globe", GPoint{10, 20}, 25000000,
+			GArea{NorthEast: GPoint{90, 180}, SouthWest: GPoint{-90, -180}}},
+		{"berlin", GPoint{52.52, 13.405}, 5000,
+			GArea{NorthEast: GPoint{52.56497, 13.47887}, SouthWest: GPoint{52.47503, 13.33113}}},
+	}
+	for _, tt := range tests {
+		got := BoundingBox(tt.center, tt.radius)
+		if !areaNear(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+		if got.NorthEast.Lng < -180 || got.NorthEast.Lng > 180 || got.SouthWest.Lng < -180 || got.SouthWest.Lng > 180 {
+			t.Errorf("%s: longitude out of range: %+v", tt.name, got)
+		}
+		if !got.Contains(tt.center) {
+			t.Errorf("%s: %+v doesn't contain its center", tt.name, got)
+		}
+	}
+}
+
+func areaNear(a, b GArea) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-4 }
+	return near(a.NorthEast.Lat, b.NorthEast.Lat) && near(a.NorthEast.Lng, b.NorthEast.Lng) &&
+		near(a.SouthWest.Lat, b.SouthWest.Lat) && near(a.SouthWest.Lng, b.SouthWest.Lng)
+}
+
+func TestBearingTo(t *testing.T) {
+	origin := GPoint{10, 20}
+	tests := []struct {
+		name string
+		to   GPoint
+		want float64
+	}{
+		{"north", GPoint{11, 20}, 0},
+		{"east", GPoint{10, 21}, 90},
+		{"south", GPoint{9, 20}, 180},
+		{"west", GPoint{10, 19}, 270},
+	}
+	for _, tt := range tests {
+		got := origin.BearingTo(tt.to)
+		if got < 0 || got >= 360 {
+			t.Errorf("%s: bearing %v out of range [0, 360)", tt.name, got)
+		}
+		//the great circle to a point due east or west starts slightly
+		//towards the pole, so allow a small deviation
+		if math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBearingToNorthIsZero(t *testing.T) {
+	for _, p := range []GPoint{{0, 0}, {-45, 170}, {52.52, 13.405}, {89, -120}} {
+		north := GPoint{p.Lat + 0.5, p.Lng}
+		if got := p.BearingTo(north); got != 0 {
+			t.Errorf("bearing from %+v due north = %v, want 0", p, got)
+		}
+	}
+}
+
+func TestAntimeridian(t *testing.T) {
+	tests := []struct {
+		area    GArea
+		center  GPoint
+		inside  []GPoint
+		outside []GPoint
+	}{
+		{
+			area:    GArea{NorthEast: GPoint{10, -170}, SouthWest: GPoint{-10, 170}},
+			center:  GPoint{0, 180},
+			inside:  []GPoint{{0, 175}, {0, 180}, {0, -180}, {5, -175}, {-10, 170}, {10, -170}},
+			outside: []GPoint{{0, 0}, {0, 169}, {0, -169}, {11, 175}},
+		},
+		{
+			area:    GArea{NorthEast: GPoint{-16, -176}, SouthWest: GPoint{-19, 178}},
+			center:  GPoint{-17.5, -179},
+			inside:  []GPoint{{-18, 179}, {-18, -179}, {-17, -176.5}},
+			outside: []GPoint{{-18, 177}, {-18, -175}, {-15, 179}},
+		},
+	}
+	for _, tt := range tests {
+		if !tt.area.CrossesAntimeridian() {
+			t.Errorf("%+v doesn't cross the antimeridian", tt.area)
+		}
+		if got := tt.area.Center(); math.Abs(got.Lat-tt.center.Lat) > 1e-9 || math.Abs(got.Lng-tt.center.Lng) > 1e-9 {
+			t.Errorf("center of %+v: got %+v, want %+v", tt.area, got, tt.center)
+		}
+		for _, p := range tt.inside {
+			if !tt.area.Contains(p) {
+				t.Errorf("%+v doesn't contain %+v", tt.area, p)
+			}
+		}
+		for _, p := range tt.outside {
+			if tt.area.Contains(p) {
+				t.Errorf("%+v contains %+v", tt.area, p)
+			}
+		}
+	}
+}
@@ -3,17 +3,27 @@
 package geopard
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"log/slog"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
 const (
 	BASE_URL = "https://maps.googleapis.com/maps/api/geocode/json?"
+
+	//DEFAULT_PING_ADDRESS is geocoded by Ping unless Options.PingAddress is set.
+	DEFAULT_PING_ADDRESS = "1600 Amphitheatre Parkway, Mountain View, CA"
 )
 
 var (
@@ -25,6 +35,24 @@ var (
 	ErrRequestDenied  = errors.New("request denied")
 	ErrInvalidRequest = errors.New("invalid request")
 	ErrUnknown        = errors.New("unkown error")
+	ErrPartialMatch   = errors.New("only partial matches")
+	ErrReplayMissing  = errors.New("no recorded response")
+	ErrURLTooLong     = errors.New("request url too long")
+	ErrUnknownBucket  = errors.New("unknown bucket")
+	ErrAmbiguous      = errors.New("ambiguous result")
+	ErrQuotaExceeded  = errors.New("daily quota exceeded")
+	ErrEmptyResults   = errors.New("status ok without results")
+	ErrPOSTNotAllowed = errors.New("post requests need a custom base url")
+	ErrQueueFull      = errors.New("too many pending requests")
+	ErrAttemptTimeout = errors.New("attempt timed out")
+	ErrCacheDisabled  = errors.New("cache is disabled")
+	ErrCacheVersion   = errors.New("unsupported cache file version")
+	ErrCoordFormat    = errors.New("coordinate format must be 'f' or 'g'")
+	ErrEmptyAddress   = errors.New("empty address")
+	//ErrConflictingParams is returned if a request doesn't have exactly one
+	//of the mutually exclusive parameters address, latlng and place_id, or
+	//components on its own.
+	ErrConflictingParams = errors.New("conflicting or missing locator parameters")
 )
 
 //Options contains all required data to create an instance of the request
@@ -47,6 +75,288 @@ type Options struct {
 	//geocoding api. This value usually should not be changed.
 	//See: https://developers.google.com/maps/documentation/geocoding/usage-limits
 	MaxQueriesPerSec int
+
+	//CoordFormat is the strconv.FormatFloat verb used to encode latitude
+	//and longitude in reverse geocoding requests, either 'f' or 'g'.
+	//Leaving it unset keeps the default of 'f' with 8 decimals. Any other
+	//verb makes every request fail with ErrCoordFormat. The api does not
+	//accept exponents, so values 'g' would print as "1e-05" are encoded
+	//with 'f' instead.
+	CoordFormat byte
+
+	//CoordPrecision is the precision used together with CoordFormat and
+	//is ignored if CoordFormat is unset. Zero selects the default, which is
+	//8 decimals for 'f' and -1 for 'g'. A value of -1 selects the smallest
+	//number of digits that represents the value exactly, which avoids
+	//trailing zeros ("52.5" instead of "52.50000000").
+	CoordPrecision int
+
+	//Logger receives a log record for every request sent to the geocoding
+	//service. Logging is disabled if Logger is nil.
+	Logger *slog.Logger
+
+	//SlowRequestThreshold makes every request taking longer, including the
+	//wait for the rate limiter, log a warning to Logger with the time spent
+	//waiting and on the network. Together with a Logger discarding debug
+	//records only slow and failed requests are logged. Zero disables it.
+	SlowRequestThreshold time.Duration
+
+	//OnResponse is called after every request to the geocoding service has
+	//finished, regardless of its outcome.
+	OnResponse func(RequestInfo)
+
+	//CorrelationIDFromContext extracts a request scoped id, e.g. a trace or
+	//request id, from the context passed to the ...Context methods. The id is
+	//added to log records and to the RequestInfo passed to hooks.
+	CorrelationIDFromContext func(context.Context) string
+
+	//OnCallStart is called when a geocoding call starts, before the cache
+	//lookup, rate limiting and retries, with the sanitized request url.
+	//The returned context is used for the call, which allows e.g. starting
+	//a tracing span. The returned function, if not nil, is called with the
+	//outcome once the call has finished. See package geopardotel.
+	OnCallStart func(ctx context.Context, url string) (context.Context, func(RequestInfo))
+
+	//AutoLanguage makes Geocode guess the language of the address from its
+	//dominant Unicode script (e.g. Cyrillic is taken as "ru", Han as "zh"
+	//or, if kana occur, as "ja") and request results in that language
+	//instead of Lang. Addresses in Latin script keep Lang. The guess is
+	//overridden by WithLang.
+	AutoLanguage bool
+
+	//Client is the http client used to send requests. If it is nil a
+	//client with DefaultTransport is used. Responses are requested gzip encoded and
+	//decompressed by the library regardless of the client's transport.
+	Client *http.Client
+
+	//ValidationAllowPartial makes ValidateAddress accept results that
+	//are only a partial match for the queried address.
+	ValidationAllowPartial bool
+
+	//ValidationMinPrecision is the least precise location type, e.g.
+	//LocationRangeInterpolated, accepted by ValidateAddress. Defaults to
+	//LocationGeometricCenter, so only approximate results are rejected.
+	ValidationMinPrecision string
+
+	//RejectPartialMatch drops all results that are only a partial match
+	//for the query. If no result remains ErrPartialMatch is returned.
+	RejectPartialMatch bool
+
+	//MaxResults caps the number of results kept per response. Results are
+	//truncated after decoding, keeping Google's original order, so only the
+	//first MaxResults results remain. Zero keeps all results.
+	MaxResults int
+
+	//RoundResults rounds the latitude and longitude of every result's
+	//location, viewport and bounds to the given number of decimals after
+	//decoding, e.g. 6 for a precision of about 10cm. Rounding is lossy:
+	//the cache only holds the rounded coordinates. Zero, the default, keeps
+	//the coordinates unchanged.
+	RoundResults int
+
+	//MaxRetries is the number of times a request is repeated if it failed
+	//with a Transient or RateLimited error (see Classify). Zero disables
+	//retries.
+	MaxRetries int
+
+	//Backoff determines how long to wait before each retry. Defaults to
+	//an ExponentialBackoff starting at 100ms and capped at 5s. If a
+	//rate limited response (HTTP 429 or OVER_QUERY_LIMIT) carries a
+	//Retry-After header, its delay is used instead, capped at the Max of
+	//an ExponentialBackoff or at one minute for other backoffs, so a
+	//misbehaving proxy can't stall a request indefinitely.
+	Backoff Backoff
+
+	//PerAttemptTimeout limits the duration of every single attempt to send
+	//a request, starting after the wait for the rate limiter, so a slow
+	//attempt can't use up the time left for retries. An attempt running
+	//out of time fails with ErrAttemptTimeout, which is retried like other
+	//Transient errors. The deadline of the context and WithTimeout still
+	//bound the whole call including all attempts and backoff sleeps; once
+	//they expire no further attempt is made. Zero disables it.
+	PerAttemptTimeout time.Duration
+
+	//Jitter randomizes the delays returned by Backoff by up to the given
+	//fraction in either direction, e.g. 0.2 turns 1s into 800ms to 1.2s.
+	//Zero disables jitter. Values above 1 are treated as 1.
+	Jitter float64
+
+	//Rand is the source of randomness for Jitter. Setting it to a seeded
+	//source makes the delays reproducible, e.g. in tests. It is used under
+	//a lock and must not be used elsewhere concurrently. If it is nil a
+	//source seeded from crypto/rand is used.
+	Rand *rand.Rand
+
+	//OnRetry is called before the backoff sleep preceding every retry with
+	//the number of the failed attempt (starting at 1), the delay that is
+	//about to be slept and the attempt's error, whose urls have the api key
+	//removed. It is called synchronously, so the sleep starts when it
+	//returns.
+	OnRetry func(attempt int, delay time.Duration, err error)
+
+	//PingAddress is the address geocoded by Ping. It must be an address
+	//that is known to yield results. Defaults to DEFAULT_PING_ADDRESS.
+	PingAddress string
+
+	//RecordDir is a directory where the raw body of every response is
+	//stored. The file name is derived from a hash of the request url
+	//without the api key. Recording is disabled if RecordDir is empty.
+	RecordDir string
+
+	//ReplayDir is a directory with responses previously stored via
+	//RecordDir. Requests are answered from there instead of the network.
+	//Replayed requests neither wait for nor consume the rate limit.
+	ReplayDir string
+
+	//ReplayStrict makes requests fail with ErrReplayMissing if ReplayDir
+	//holds no response for them. Otherwise they fall back to the network
+	//(and are recorded if RecordDir is set).
+	ReplayStrict bool
+
+	//Lean skips decoding viewports, bounds and address components. Only the
+	//fields of LeanResult are populated in the returned results, which
+	//noticeably reduces allocations and parse time for large responses.
+	Lean bool
+
+	//Fields selects the parts of the results that are decoded, the others
+	//are skipped by the json decoder and left empty. Place id, types and
+	//the partial match flag are always decoded. Zero, the default, decodes
+	//all fields. Fields is ignored if Lean is set.
+	Fields Fields
+
+	//MaxURLLength is the maximum length of a request url. Longer requests
+	//are not sent and fail with ErrURLTooLong instead of risking truncation
+	//by proxies. Defaults to 8192. It doesn't apply with UsePOST, which
+	//sends the parameters in the body.
+	MaxURLLength int
+
+	//OnRefill is called whenever the rate limiter replenishes its request
+	//slots, with the time of the refill. It is called on a new goroutine so
+	//it can't delay the rate limiter.
+	OnRefill func(time.Time)
+
+	//BiasRadius is the radius in meters of the bounds WithLocationBias
+	//creates around its point. Defaults to 5000.
+	BiasRadius float64
+
+	//BaseURL replaces the url requests are sent to, e.g. to route them
+	//through a proxy or a compatible gateway, or to maps.google.cn for
+	//users in China. It must end right before the query string, like
+	//BASE_URL. Google has no regional or data residency hosts for the
+	//geocoding api, every request is served from maps.googleapis.com.
+	//See: https://developers.google.com/maps/faq#china_ws_access
+	BaseURL string
+
+	//UsePOST sends requests as POST with the parameters in a json object
+	//body, e.g. {"address":"...","key":"..."}, instead of the query string.
+	//It is meant for compatible gateways requiring it and only valid with
+	//a BaseURL that doesn't point to Google, otherwise all requests fail
+	//with ErrPOSTNotAllowed.
+	UsePOST bool
+
+	//CacheTTL enables an in-memory cache of successful responses keyed by
+	//the request url without the api key. Cached responses are returned
+	//without sending a request for CacheTTL. Afterwards they are revalidated
+	//with If-None-Match if the response carried an ETag, which caching
+	//proxies in front of Google may answer with 304 Not Modified. Zero
+	//disables the cache. Entries are never evicted, only replaced.
+	CacheTTL time.Duration
+
+	//OnPartialRetryComponents are added to a Geocode request that only
+	//yielded partial matches, and the request is sent again. The tightened
+	//response replaces the original one if it contains a full match.
+	//Each such retry consumes another request of the quota.
+	OnPartialRetryComponents Components
+
+	//AddressSeparator separates the parts of addresses assembled by the
+	//processor's ShortAddress method. It defaults to ", ". The methods of
+	//GResult don't know the processor and always use ", " unless called
+	//with an explicit separator, e.g. GResult.ShortAddressSep. Addresses
+	//formatted by Google, like GResult.FormattedAddr, are not affected.
+	AddressSeparator string
+
+	//USZip5Fallback makes Geocode retry a query containing a US ZIP+4 code
+	//(e.g. "94043-1351"), either in the address or in the postal_code
+	//component, with the 5 digit ZIP if the query found nothing or only
+	//partial matches, as US addresses sometimes geocode worse with ZIP+4.
+	//The retry runs before any RelaxOnZeroResults retries and consumes
+	//another request of the quota.
+	USZip5Fallback bool
+
+	//RelaxOnZeroResults makes Geocode retry a query that found nothing with
+	//a simplified address. Every retry removes the last token of the
+	//address, tokens being separated by whitespace and commas, e.g.
+	//"1 Main St, Apt 3" becomes "1 Main St, Apt" and then "1 Main St".
+	//The first token is never removed. At most MaxRelaxations retries
+	//(default 2) are made and GResponse.RelaxLevel tells how many were
+	//needed. Each retry consumes another request of the quota.
+	RelaxOnZeroResults bool
+	MaxRelaxations     int
+
+	//StatusMapper converts the status field of a response to the error
+	//returned to the caller, nil meaning success. It allows to talk to
+	//compatible backends that report statuses differently (see BaseURL).
+	//Returning one of the Err... variables keeps Classify and retries
+	//working. Defaults to GoogleStatus.
+	StatusMapper func(status string) error
+
+	//Adaptive makes the rate limiter react to OVER_QUERY_LIMIT responses by
+	//halving the number of requests allowed per period. Every period
+	//completed without hitting the limit raises it by one again, up to
+	//MaxQueriesPerSec. The current rate is reported by Stats.
+	Adaptive bool
+
+	//MaxPending bounds the number of requests waiting for the rate limiter.
+	//If it is reached, further requests fail immediately with ErrQueueFull,
+	//or wait for a free place if BlockWhenFull is set. Failing fast sheds
+	//load a caller can't handle anyway, blocking only bounds the queue of
+	//prioritized waiters while goroutines still pile up in front of it.
+	//Zero, the default, doesn't bound the queue.
+	MaxPending    int
+	BlockWhenFull bool
+
+	//Limiter is a rate limiter shared with other request processors, e.g.
+	//for several languages using the same api key, so together they stay
+	//within its rate. If it is set, MaxQueriesPerSec, Buckets, Adaptive,
+	//OnRefill, MaxPending and BlockWhenFull are taken from the options
+	//the limiter was created with. The limiter is owned by the caller:
+	//closing a processor doesn't stop it, Limiter.Close must be called once
+	//all processors using it are no longer needed.
+	Limiter *Limiter
+
+	//BatchDedup makes GeocodeBatch send a single request for duplicate
+	//addresses. It defaults to DedupNone.
+	BatchDedup BatchDedup
+
+	//Unmarshal decodes the response body, allowing to plug in a faster
+	//json implementation with the same semantics, like json-iterator.
+	//Defaults to json.Unmarshal.
+	Unmarshal func(data []byte, v interface{}) error
+
+	//Buckets splits the rate limit between named workloads, mapping each
+	//bucket name to its weight, e.g. {"interactive": 70, "bulk": 30}. When
+	//requests of several buckets are waiting, the slots are shared in
+	//proportion to the weights. Slots a bucket doesn't use are available to
+	//the others. Requests are assigned via WithBucket or GeocodeVia; all
+	//other requests use DefaultBucket, which has a weight of 1 unless it is
+	//configured here as well.
+	Buckets map[string]int
+
+	//DailyQuota is the number of requests budgeted per day. The processor
+	//counts the requests it sends and resets the count at midnight UTC,
+	//when Google resets its quotas. See RemainingQuota. Zero disables
+	//tracking.
+	DailyQuota int
+
+	//EnforceDailyQuota makes requests fail with ErrQuotaExceeded instead of
+	//being sent once DailyQuota is used up.
+	EnforceDailyQuota bool
+
+	//EmptyResultsError is returned if Google answers with status OK but
+	//without any results, which happens in rare cases. Defaults to
+	//ErrZeroResults, set it to ErrEmptyResults to tell those responses
+	//apart from regular ZERO_RESULTS.
+	EmptyResultsError error
 }
 
 //GetInstance is a stub method for creating an instance of the request
@@ -61,59 +371,208 @@ func GetInstance() *requestProcessor {
 //a new instance.
 func Instance(opts Options) *requestProcessor {
 	once.Do(func() {
-		instance = &requestProcessor{
-			apiKey:           opts.ApiKey,
-			lang:             "en",
-			maxQueriesPerSec: 10,
-		}
-		if opts.Lang != "" {
-			instance.lang = opts.Lang
-		}
-		if opts.MaxQueriesPerSec > 0 {
-			instance.maxQueriesPerSec = opts.MaxQueriesPerSec
-		}
-
-		//init the request throttling
-		instance.quit = make(chan int)
-		instance.throttle = make(chan int, instance.maxQueriesPerSec)
-		//allow requests for first time so we don't have to wait for the ticker period
-		instance.allowRequests()
-		instance.ticker = time.NewTicker(5 * time.Second)
-		go instance.multiTick()
+		instance = New(opts)
 	})
 	return instance
 }
 
-func (r *requestProcessor) Destroy() {
-	close(r.quit)
-	close(r.throttle)
+//New creates a request processor that is independent of the singleton and
+//has its own rate limiter. It is meant for tests and for setups that need
+//differently configured processors. Note that processors with the same api
+//key share Google's quota, so their combined rate must not exceed it.
+func New(opts Options) *requestProcessor {
+	r := &requestProcessor{
+		apiKey:           opts.ApiKey,
+		logger:           opts.Logger,
+		onResponse:       opts.OnResponse,
+		slowThreshold:    opts.SlowRequestThreshold,
+		correlationID:    opts.CorrelationIDFromContext,
+		onCallStart:      opts.OnCallStart,
+		autoLang:         opts.AutoLanguage,
+		client:           &http.Client{Transport: DefaultTransport()},
+		lang:             "en",
+		maxQueriesPerSec: defaultMaxQueriesPerSec,
+		coordFmt:         'f',
+		coordPrec:        8,
+		rejectPartial:    opts.RejectPartialMatch,
+		maxResults:       opts.MaxResults,
+		roundResults:     opts.RoundResults,
+		batchDedup:       opts.BatchDedup,
+		maxRetries:       opts.MaxRetries,
+		onRetry:          opts.OnRetry,
+		attemptTimeout:   opts.PerAttemptTimeout,
+		backoff:          ExponentialBackoff{Base: 100 * time.Millisecond, Max: 5 * time.Second},
+		pingAddress:      DEFAULT_PING_ADDRESS,
+		recordDir:        opts.RecordDir,
+		replayDir:        opts.ReplayDir,
+		replayStrict:     opts.ReplayStrict,
+		lean:             opts.Lean,
+		fields:           opts.Fields,
+		maxURLLength:     8192,
+		biasRadius:       5000,
+		baseURL:          opts.BaseURL,
+		usePOST:          opts.UsePOST,
+		cache:            newCache(opts.CacheTTL),
+		partialRetry:     opts.OnPartialRetryComponents,
+		addrSep:          defaultAddressSeparator,
+		zip5Fallback:     opts.USZip5Fallback,
+		statusMapper:     GoogleStatus,
+		stats:            &stats{},
+		unmarshal:        json.Unmarshal,
+		quota:            newQuota(opts.DailyQuota, opts.EnforceDailyQuota),
+		jitter:           newJitter(opts.Jitter, opts.Rand),
+		emptyErr:         ErrZeroResults,
+		validation: validationPolicy{
+			allowPartial: opts.ValidationAllowPartial,
+			minPrecision: LocationGeometricCenter,
+		},
+	}
+	if opts.Lang != "" {
+		r.lang = opts.Lang
+	}
+	if opts.MaxQueriesPerSec > 0 {
+		r.maxQueriesPerSec = opts.MaxQueriesPerSec
+	}
+	if opts.Client != nil {
+		r.client = opts.Client
+	}
+	if r.baseURL == "" {
+		r.baseURL = BASE_URL
+	}
+	if opts.UsePOST && (opts.BaseURL == "" || isGoogleURL(opts.BaseURL)) {
+		r.configErr = ErrPOSTNotAllowed
+	}
+	if opts.BiasRadius > 0 {
+		r.biasRadius = opts.BiasRadius
+	}
+	if opts.MaxURLLength > 0 {
+		r.maxURLLength = opts.MaxURLLength
+	}
+	if opts.RelaxOnZeroResults {
+		r.maxRelax = 2
+		if opts.MaxRelaxations > 0 {
+			r.maxRelax = opts.MaxRelaxations
+		}
+	}
+	if opts.PingAddress != "" {
+		r.pingAddress = opts.PingAddress
+	}
+	if opts.EmptyResultsError != nil {
+		r.emptyErr = opts.EmptyResultsError
+	}
+	if opts.Unmarshal != nil {
+		r.unmarshal = opts.Unmarshal
+	}
+	if opts.StatusMapper != nil {
+		r.statusMapper = opts.StatusMapper
+	}
+	if opts.AddressSeparator != "" {
+		r.addrSep = opts.AddressSeparator
+	}
+	if opts.Backoff != nil {
+		r.backoff = opts.Backoff
+	}
+	if opts.ValidationMinPrecision != "" {
+		r.validation.minPrecision = opts.ValidationMinPrecision
+	}
+	if opts.CoordFormat == 'g' {
+		r.coordFmt = 'g'
+		r.coordPrec = -1
+	}
+	if opts.CoordFormat != 0 && opts.CoordPrecision != 0 {
+		r.coordPrec = opts.CoordPrecision
+	}
+	if opts.CoordFormat != 0 && opts.CoordFormat != 'f' && opts.CoordFormat != 'g' {
+		r.configErr = ErrCoordFormat
+	}
+
+	//init the request throttling
+	if opts.Limiter != nil {
+		r.limiter = opts.Limiter.l
+		r.maxQueriesPerSec = r.limiter.max
+		r.sharedLimiter = true
+	} else {
+		r.limiter = newLimiter(r.maxQueriesPerSec, opts)
+	}
+	return r
 }
 
-type requestProcessor struct {
-	apiKey           string
-	lang             string
-	maxQueriesPerSec int
-	throttle         chan int
-	quit             chan int
-	ticker           *time.Ticker
+//WithLanguage returns a shallow copy of the request processor that uses the
+//given language by default. The copy shares the rate limiter, http client
+//and all other state with the original, so together they never exceed the
+//configured rate. Closing either of them stops the shared rate limiter.
+func (r *requestProcessor) WithLanguage(lang string) *requestProcessor {
+	clone := *r
+	clone.lang = lang
+	return &clone
 }
 
-func (r *requestProcessor) allowRequests() {
-	for i := 1; i <= r.maxQueriesPerSec; i++ {
-		r.throttle <- i
+//Close stops the rate limiter of the request processor and all its clones.
+//Requests waiting for the rate limiter are released. A limiter passed via
+//Options.Limiter is not stopped. Close may be called multiple times and
+//always returns nil, it implements io.Closer.
+func (r *requestProcessor) Close() error {
+	if !r.sharedLimiter {
+		r.limiter.stop()
 	}
+	return nil
 }
 
-func (r *requestProcessor) multiTick() {
-	for {
-		select {
-		case <-r.quit:
-			r.ticker.Stop()
-			return
-		case <-r.ticker.C:
-			r.allowRequests()
-		}
-	}
+var _ io.Closer = (*requestProcessor)(nil)
+
+//Destroy works like Close.
+//
+//Deprecated: Use Close instead.
+func (r *requestProcessor) Destroy() {
+	r.Close()
+}
+
+type requestProcessor struct {
+	apiKey           string
+	lang             string
+	maxQueriesPerSec int
+	coordFmt         byte
+	coordPrec        int
+	logger           *slog.Logger
+	onResponse       func(RequestInfo)
+	slowThreshold    time.Duration
+	correlationID    func(context.Context) string
+	onCallStart      func(context.Context, string) (context.Context, func(RequestInfo))
+	autoLang         bool
+	client           *http.Client
+	validation       validationPolicy
+	rejectPartial    bool
+	maxResults       int
+	roundResults     int
+	batchDedup       BatchDedup
+	maxRetries       int
+	onRetry          func(int, time.Duration, error)
+	attemptTimeout   time.Duration
+	backoff          Backoff
+	pingAddress      string
+	recordDir        string
+	replayDir        string
+	replayStrict     bool
+	lean             bool
+	fields           Fields
+	maxURLLength     int
+	biasRadius       float64
+	baseURL          string
+	usePOST          bool
+	configErr        error
+	cache            *cache
+	partialRetry     Components
+	maxRelax         int
+	addrSep          string
+	zip5Fallback     bool
+	statusMapper     func(string) error
+	limiter          *limiter
+	sharedLimiter    bool
+	stats            *stats
+	unmarshal        func([]byte, interface{}) error
+	quota            *quota
+	jitter           *jitter
+	emptyErr         error
 }
 
 //The following structs are for parsing the json response from
@@ -122,14 +581,20 @@ type (
 	GResponse struct {
 		Status  string    `json:"status"`
 		Results []GResult `json:"results"`
+		//RelaxLevel is the number of times the address was simplified
+		//before results were found (see Options.RelaxOnZeroResults).
+		RelaxLevel int `json:"-"`
 	}
 	GResult struct {
-		PlaceId        string           `json:"place_id"`
+		PlaceId        PlaceID          `json:"place_id"`
 		FormattedAddr  string           `json:"formatted_address"`
 		Geometry       GGeometry        `json:"geometry"`
 		PartialMatch   bool             `json:"partial_match"`
 		AddrComponents []GAddrComponent `json:"address_components"`
 		Types          []string         `json:"types"`
+		//PostcodeLocalities lists the localities within a postal code
+		//result. It is only set for results of type postal_code.
+		PostcodeLocalities []string `json:"postcode_localities,omitempty"`
 	}
 	GGeometry struct {
 		Location     GPoint `json:"location"`
@@ -152,64 +617,496 @@ type (
 	}
 )
 
-func (r *requestProcessor) processRequest(url string) (GResponse, error) {
-	response := GResponse{}
+func (r *requestProcessor) processRequestContext(ctx context.Context, req *request) (GResponse, error) {
+	var finish func(RequestInfo)
+	if r.onCallStart != nil {
+		ctx, finish = r.onCallStart(ctx, sanitizeURL(req.url()))
+	}
+	start := time.Now()
 
-	//wait for throttling to give green light
-	//this will block until there are 'free' slots for requests
-	<-r.throttle
-	//then send request
-	resp, err := http.Get(url)
+	response, err := r.execute(ctx, req)
+	if err == nil {
+		req.postProcess(&response)
+	}
+
+	if finish != nil {
+		info := RequestInfo{
+			URL:      sanitizeURL(req.url()),
+			Status:   response.Status,
+			Results:  len(response.Results),
+			Duration: time.Since(start),
+			Err:      err,
+		}
+		if r.correlationID != nil {
+			info.CorrelationID = r.correlationID(ctx)
+		}
+		finish(info)
+	}
+	return response, err
+}
+
+//execute sends the request, or answers it from the cache, including all
+//retries.
+func (r *requestProcessor) execute(ctx context.Context, req *request) (GResponse, error) {
+	if req.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.timeout)
+		defer cancel()
+	}
+
+	if r.configErr != nil {
+		return GResponse{}, r.configErr
+	}
+
+	if err := req.validate(); err != nil {
+		return GResponse{}, err
+	}
+	url := req.url()
+	//POST requests carry the parameters in the body
+	if !r.usePOST && len(url) > r.maxURLLength {
+		return GResponse{}, ErrURLTooLong
+	}
+
+	c := &call{url: url, bucket: req.bucket, priority: req.priority, raw: req.raw}
+	if r.cache != nil {
+		c.cacheKey = CacheKey(req.params)
+		if entry, ok := r.cache.get(c.cacheKey); ok && !req.noCache {
+			if r.cache.fresh(entry) && (req.maxAge <= 0 || r.cache.age(entry) < req.maxAge) {
+				return entry.Response, nil
+			}
+			//a stale entry can still be revalidated by a caching proxy
+			if entry.ETag != "" {
+				c.cached = &entry
+			}
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		response, err := r.attempt(ctx, c)
+		if err == nil && r.cache != nil {
+			r.cache.put(c.cacheKey, cacheEntry{Response: response, ETag: c.etag})
+		}
+		if attempt > r.maxRetries {
+			return response, err
+		}
+		if class := Classify(err); class != Transient && class != RateLimited || errors.Is(err, ErrQueueFull) {
+			return response, err
+		}
+		delay := r.jitter.apply(r.backoff.NextDelay(attempt))
+		if c.retryAfter > 0 && Classify(err) == RateLimited {
+			//the server knows best when it accepts requests again
+			delay = capRetryAfter(c.retryAfter, r.backoff)
+		}
+		if r.onRetry != nil {
+			r.onRetry(attempt, delay, sanitizeError(err))
+		}
+		if serr := sleep(ctx, delay); serr != nil {
+			return response, err
+		}
+	}
+}
+
+//call holds the state of a single request to the geocoding service that
+//is shared by all its attempts.
+type call struct {
+	url      string
+	bucket   string
+	priority int
+	cacheKey string
+	//cached is the stale cache entry that is revalidated via its ETag
+	cached *cacheEntry
+	//etag is the ETag header of the last response
+	etag string
+	//notModified is set if the last response was 304 Not Modified
+	notModified bool
+	//wait is the time the last attempt waited for the rate limiter
+	wait time.Duration
+	//retryAfter is the delay requested by the Retry-After header of the
+	//last response, zero if there was none
+	retryAfter time.Duration
+	//raw receives a copy of the last response body if it is not nil
+	raw *[]byte
+}
+
+//attempt sends a single request and reports it to the logger and hooks.
+func (r *requestProcessor) attempt(ctx context.Context, c *call) (GResponse, error) {
+	start := time.Now()
+	c.wait, c.retryAfter = 0, 0
+	if c.raw != nil {
+		*c.raw = nil
+	}
+	response, err := r.sendRequest(ctx, c)
+	r.stats.count(response.Status, err)
+	r.limiter.feedback(err)
+
+	//skip all instrumentation if nobody is listening
+	if r.logger != nil || r.onResponse != nil {
+		r.observe(ctx, RequestInfo{
+			URL:          sanitizeURL(c.url),
+			Status:       response.Status,
+			Results:      len(response.Results),
+			Duration:     time.Since(start),
+			WaitDuration: c.wait,
+			Err:          err,
+		})
+	}
+
+	return response, err
+}
 
+func (r *requestProcessor) sendRequest(ctx context.Context, c *call) (GResponse, error) {
+	response := GResponse{}
+
+	body, err := r.replay(c.url)
 	if err != nil {
 		return response, err
 	}
+	if body == nil {
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if err = r.fetch(ctx, c, buf); err != nil {
+			return response, deadlineError(ctx, err)
+		}
+		if c.notModified {
+			return c.cached.Response, nil
+		}
+		body = buf.Bytes()
+		if err = r.record(c.url, body); err != nil {
+			return response, err
+		}
+	}
 
-	defer resp.Body.Close()
+	if c.raw != nil {
+		//the body is returned to the pool, keep a copy
+		*c.raw = append([]byte(nil), body...)
+	}
 
 	//parse json response into temporary struct
-	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+	if err = r.decode(body, &response); err != nil {
+		return response, deadlineError(ctx, newDecodeError(c.url, body, err))
+	}
+
+	if err = r.statusMapper(response.Status); err != nil {
 		return response, err
 	}
+	if len(response.Results) == 0 {
+		return response, r.emptyErr
+	}
 
-	switch response.Status {
+	return response, r.postProcess(&response)
+}
+
+//GoogleStatus is the default Options.StatusMapper. It maps the status
+//strings of the Google geocoding api to errors:
+//
+//	OK                nil
+//	ZERO_RESULTS      ErrZeroResults
+//	OVER_QUERY_LIMIT  ErrOverLimit
+//	REQUEST_DENIED    ErrRequestDenied
+//	INVALID_REQUEST   ErrInvalidRequest
+//	UNKNOWN_ERROR     ErrUnknown
+//
+//Any other status is treated like OK.
+func GoogleStatus(status string) error {
+	switch status {
 	case "OK":
 		break
 	case "ZERO_RESULTS":
-		return response, ErrZeroResults
+		return ErrZeroResults
 	case "OVER_QUERY_LIMIT":
-		return response, ErrOverLimit
+		return ErrOverLimit
 	case "REQUEST_DENIED":
-		return response, ErrRequestDenied
+		return ErrRequestDenied
 	case "INVALID_REQUEST":
-		return response, ErrInvalidRequest
-	case "UNKOWN_ERROR":
-		return response, ErrUnknown
+		return ErrInvalidRequest
+	case "UNKNOWN_ERROR", "UNKOWN_ERROR":
+		return ErrUnknown
+	}
+
+	return nil
+}
+
+//newHTTPRequest creates the http request for a request url, sending the
+//query parameters as json body if Options.UsePOST is set.
+func (r *requestProcessor) newHTTPRequest(ctx context.Context, rawURL string) (*http.Request, error) {
+	if !r.usePOST {
+		return http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	params := make(map[string]string, len(q))
+	for k := range q {
+		params[k] = q.Get(k)
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = ""
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+//fetch waits for the rate limiter and sends the request. The decompressed
+//response body is written to buf. Nothing is written if the response is a
+//304 Not Modified for a revalidated cache entry.
+func (r *requestProcessor) fetch(ctx context.Context, c *call, buf *bytes.Buffer) error {
+	//wait for throttling to give green light
+	//this will block until there are 'free' slots for requests
+	//or the context is done
+	waitStart := time.Now()
+	err := r.limiter.wait(ctx, c.bucket, c.priority)
+	c.wait = time.Since(waitStart)
+	if err != nil {
+		return err
+	}
+	if r.quota != nil {
+		if err := r.quota.take(); err != nil {
+			r.limiter.release()
+			return err
+		}
 	}
 
-	return response, nil
+	if r.attemptTimeout <= 0 {
+		return r.send(ctx, c, buf)
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, r.attemptTimeout)
+	defer cancel()
+	err = r.send(attemptCtx, c, buf)
+	if err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		//only this attempt ran out of time, report it as retryable
+		return ErrAttemptTimeout
+	}
+	return err
+}
+
+//send sends the request and writes the decompressed response body to buf.
+func (r *requestProcessor) send(ctx context.Context, c *call, buf *bytes.Buffer) error {
+	req, err := r.newHTTPRequest(ctx, c.url)
+	if err != nil {
+		return err
+	}
+	//ask for compression explicitly so it also works with custom
+	//transports that have automatic compression disabled
+	req.Header.Set("Accept-Encoding", "gzip")
+	if c.cached != nil {
+		req.Header.Set("If-None-Match", c.cached.ETag)
+	}
+	resp, err := r.client.Do(req)
+
+	if err != nil {
+		return sanitizeError(err)
+	}
+
+	defer resp.Body.Close()
+
+	c.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return ErrOverLimit
+	}
+
+	c.etag = resp.Header.Get("ETag")
+	c.notModified = c.cached != nil && resp.StatusCode == http.StatusNotModified
+	if c.notModified {
+		//the proxy confirmed the cached response, keep its ETag
+		c.etag = c.cached.ETag
+		return nil
+	}
+
+	body := io.Reader(resp.Body)
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	_, err = buf.ReadFrom(body)
+	return err
+}
+
+//postProcess applies the client side result policies to a successful
+//response.
+func (r *requestProcessor) postProcess(response *GResponse) error {
+	if r.rejectPartial {
+		exact := response.Results[:0]
+		for _, res := range response.Results {
+			if !res.PartialMatch {
+				exact = append(exact, res)
+			}
+		}
+		if len(exact) == 0 && len(response.Results) > 0 {
+			response.Results = exact
+			return ErrPartialMatch
+		}
+		response.Results = exact
+	}
+
+	if r.maxResults > 0 && len(response.Results) > r.maxResults {
+		//copy so the dropped results can be garbage collected
+		response.Results = append([]GResult(nil), response.Results[:r.maxResults]...)
+	}
+
+	if r.roundResults > 0 {
+		for i := range response.Results {
+			response.Results[i].Geometry.round(r.roundResults)
+		}
+	}
+
+	return nil
+}
+
+//formatCoord encodes a single coordinate for use in a query url
+//according to the configured float format.
+func (r *requestProcessor) formatCoord(f float64) string {
+	s := strconv.FormatFloat(f, r.coordFmt, r.coordPrec, 64)
+	if strings.ContainsRune(s, 'e') {
+		//'g' switches to exponents for very small and large values
+		s = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return s
 }
 
 //ReverseGeocode returns a GResponse object for the given latitude, longitude pair.
 //It contains all information offered by the google geocoding api.
-func (r *requestProcessor) ReverseGeocode(lat, lng float64) (GResponse, error) {
-	//query url
-	url := BASE_URL +
-		"latlng=" + strconv.FormatFloat(lat, 'f', 8, 64) + "," + strconv.FormatFloat(lng, 'f', 8, 64) +
-		"&language=" + r.lang +
-		"&key=" + r.apiKey
+func (r *requestProcessor) ReverseGeocode(lat, lng float64, opts ...RequestOption) (GResponse, error) {
+	return r.ReverseGeocodeContext(context.Background(), lat, lng, opts...)
+}
+
+//ReverseGeocodeContext works like ReverseGeocode but aborts waiting for
+//the rate limiter and the request itself when ctx is done.
+func (r *requestProcessor) ReverseGeocodeContext(ctx context.Context, lat, lng float64, opts ...RequestOption) (GResponse, error) {
+	req := r.newRequest(opts)
+	req.params.Set("latlng", r.formatCoord(lat)+","+r.formatCoord(lng))
 
-	return r.processRequest(url)
+	return r.processRequestContext(ctx, req)
 }
 
 //Geocode returns a GResponse object for the given address string.
 //It contains all information offered by the google geocoding api.
-func (r *requestProcessor) Geocode(address string) (GResponse, error) {
-	//query url
-	url := BASE_URL +
-		"address=" + url.QueryEscape(address) +
-		"&language=" + r.lang +
-		"&key=" + r.apiKey
-
-	return r.processRequest(url)
+func (r *requestProcessor) Geocode(address string, opts ...RequestOption) (GResponse, error) {
+	return r.GeocodeContext(context.Background(), address, opts...)
+}
+
+//GeocodeContext works like Geocode but aborts waiting for the rate
+//limiter and the request itself when ctx is done.
+func (r *requestProcessor) GeocodeContext(ctx context.Context, address string, opts ...RequestOption) (GResponse, error) {
+	if r.autoLang {
+		if lang, ok := detectLanguage(address); ok {
+			//prepended so an explicit WithLang wins
+			opts = append([]RequestOption{WithLang(lang)}, opts...)
+		}
+	}
+
+	resp, err := r.geocode(ctx, address, opts)
+	if r.zip5Fallback && (Classify(err) == ZeroResults || errors.Is(err, ErrPartialMatch) || err == nil && resp.allPartial()) {
+		if zipAddress, zipOpts, ok := r.zip5Query(address, opts); ok {
+			//a partial match is only replaced by a full one
+			if zipResp, zipErr := r.geocode(ctx, zipAddress, zipOpts); zipErr == nil && (err != nil || !zipResp.allPartial()) {
+				resp, err = zipResp, zipErr
+			}
+			address, opts = zipAddress, zipOpts
+		}
+	}
+	for level := 1; level <= r.maxRelax && Classify(err) == ZeroResults; level++ {
+		relaxed, ok := relaxAddress(address)
+		if !ok {
+			break
+		}
+		address = relaxed
+		resp, err = r.geocode(ctx, address, opts)
+		resp.RelaxLevel = level
+	}
+	return resp, err
+}
+
+//geocode sends a single geocoding request, followed by a tightened one if
+//it only yielded partial matches and Options.OnPartialRetryComponents is set.
+func (r *requestProcessor) geocode(ctx context.Context, address string, opts []RequestOption) (GResponse, error) {
+	req := r.newRequest(opts)
+	req.params.Set("address", address)
+
+	resp, err := r.processRequestContext(ctx, req)
+	if len(r.partialRetry) > 0 && (errors.Is(err, ErrPartialMatch) || err == nil && resp.allPartial()) {
+		//the components are appended last so they win over opts
+		req = r.newRequest(append(opts[:len(opts):len(opts)], WithComponents(r.partialRetry)))
+		req.params.Set("address", address)
+		if tightened, terr := r.processRequestContext(ctx, req); terr == nil && !tightened.allPartial() {
+			return tightened, nil
+		}
+	}
+
+	return resp, err
+}
+
+//Ping checks connectivity and the api key by geocoding a known address
+//(see Options.PingAddress). It returns nil if Google answered with status
+//OK and the request's error otherwise, e.g. ErrRequestDenied for an invalid
+//key. The request bypasses the cache and none of the fallbacks of
+//GeocodeContext (AutoLanguage, USZip5Fallback, RelaxOnZeroResults,
+//OnPartialRetryComponents) apply, so every call consumes one request of
+//the quota, plus any retries configured by Options.MaxRetries.
+func (r *requestProcessor) Ping(ctx context.Context) error {
+	req := r.newRequest([]RequestOption{WithNoCache()})
+	req.params.Set("address", r.pingAddress)
+	_, err := r.processRequestContext(ctx, req)
+	return err
+}
+
+//GeocodePriority works like GeocodeContext with the given priority for the
+//rate limiter (see WithPriority). It allows interactive requests to overtake
+//bulk requests sharing the same processor.
+func (r *requestProcessor) GeocodePriority(ctx context.Context, address string, priority int, opts ...RequestOption) (GResponse, error) {
+	return r.GeocodeContext(ctx, address, append(opts[:len(opts):len(opts)], WithPriority(priority))...)
+}
+
+//GeocodeVia works like GeocodeContext but draws the request's slot from
+//the given bucket of the rate limit (see Options.Buckets).
+func (r *requestProcessor) GeocodeVia(ctx context.Context, bucket, address string, opts ...RequestOption) (GResponse, error) {
+	return r.GeocodeContext(ctx, address, append(opts[:len(opts):len(opts)], WithBucket(bucket))...)
+}
+
+//AreaAdmin returns the administrative area containing the center of a,
+//found by reverse geocoding the center restricted to the result types
+//administrative_area_level_1 and administrative_area_level_2. Google
+//returns the most specific area first, which is the one returned.
+func (r *requestProcessor) AreaAdmin(ctx context.Context, a GArea, opts ...RequestOption) (GResult, error) {
+	center := a.Center()
+	opts = append(opts[:len(opts):len(opts)], WithResultType("administrative_area_level_1", "administrative_area_level_2"))
+	resp, err := r.ReverseGeocodeContext(ctx, center.Lat, center.Lng, opts...)
+	if err != nil {
+		return GResult{}, err
+	}
+	if len(resp.Results) == 0 {
+		return GResult{}, ErrZeroResults
+	}
+	return resp.Results[0], nil
+}
+
+//GeocodeRawBytes geocodes address with a single request and returns the
+//decoded response together with its body exactly as sent by the server,
+//after gzip decompression, e.g. to store it for auditing or to parse it
+//again later. The request always asks the geocoding service as the cache
+//only holds decoded responses, see WithNoCache. None of the fallbacks of
+//GeocodeContext (AutoLanguage, USZip5Fallback, RelaxOnZeroResults,
+//OnPartialRetryComponents) apply, so the body always belongs to the
+//returned response. With retries it is the body of the last attempt. The
+//body is returned for failed requests too, if the server answered.
+func (r *requestProcessor) GeocodeRawBytes(ctx context.Context, address string, opts ...RequestOption) (GResponse, []byte, error) {
+	var raw []byte
+	opts = append(opts[:len(opts):len(opts)], WithNoCache(), func(req *request) { req.raw = &raw })
+	req := r.newRequest(opts)
+	req.params.Set("address", address)
+	resp, err := r.processRequestContext(ctx, req)
+	return resp, raw, err
 }
@@ -0,0 +1,212 @@
+package geopard
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testResponse = `{"status":"OK","results":[{"formatted_address":"Unter den Linden 1, 10117 Berlin, Germany","place_id":"p1","geometry":{"location":{"lat":52.517,"lng":13.397},"location_type":"ROOFTOP"}}]}`
+
+func TestFormatCoord(t *testing.T) {
+	tests := []struct {
+		format byte
+		prec   int
+		in     float64
+		want   string
+	}{
+		{0, 0, 52.5, "52.50000000"},
+		{'f', 0, 52.5, "52.50000000"},
+		{'f', 3, 52.5, "52.500"},
+		{'f', -1, 52.5, "52.5"},
+		{'g', 0, 52.5, "52.5"},
+		{'g', 0, 0.00001, "0.00001"},
+		{'g', 3, 123456, "123456"},
+	}
+	for _, tt := range tests {
+		r := New(Options{CoordFormat: tt.format, CoordPrecision: tt.prec})
+		if r.configErr != nil {
+			t.Errorf("format %q: unexpected error %v", tt.format, r.configErr)
+		}
+		if got := r.formatCoord(tt.in); got != tt.want {
+			t.Errorf("format %q, precision %d: formatCoord(%v) = %q, want %q", tt.format, tt.prec, tt.in, got, tt.want)
+		}
+		r.Close()
+	}
+}
+
+func TestCoordFormatInvalid(t *testing.T) {
+	r := New(Options{CoordFormat: 'e'})
+	defer r.Close()
+	if !errors.Is(r.configErr, ErrCoordFormat) {
+		t.Fatalf("got %v, want ErrCoordFormat", r.configErr)
+	}
+}
+
+func TestGzipResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") {
+			t.Errorf("Accept-Encoding = %q, want gzip", req.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(testResponse))
+		gz.Close()
+	}))
+	defer srv.Close()
+
+	clients := map[string]*http.Client{
+		"default":              nil,
+		"compression disabled": {Transport: &http.Transport{DisableCompression: true}},
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			r := New(Options{BaseURL: srv.URL + "/?", Client: client})
+			defer r.Close()
+			resp, err := r.Geocode("Unter den Linden 1, Berlin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Results) != 1 || resp.Results[0].PlaceId != "p1" {
+				t.Fatalf("unexpected results %+v", resp.Results)
+			}
+		})
+	}
+}
+
+func TestPingSendsOneRequest(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`{"status":"ZERO_RESULTS","results":[]}`))
+	}))
+	defer srv.Close()
+
+	r := New(Options{
+		BaseURL:                  srv.URL + "/?",
+		PingAddress:              "1600 Amphitheatre Parkway, Mountain View, CA 94043-1351",
+		CacheTTL:                 time.Hour,
+		USZip5Fallback:           true,
+		RelaxOnZeroResults:       true,
+		OnPartialRetryComponents: Components{"country": "US"},
+	})
+	defer r.Close()
+
+	for i := 1; i <= 2; i++ {
+		if err := r.Ping(context.Background()); !errors.Is(err, ErrZeroResults) {
+			t.Fatalf("got %v, want ErrZeroResults", err)
+		}
+		if got := requests.Load(); got != int32(i) {
+			t.Fatalf("after %d pings the server got %d requests", i, got)
+		}
+	}
+}
+
+func TestGeocodeRawBytesMatchesResponse(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		if req.URL.Query().Has("components") {
+			w.Write([]byte(testResponse))
+			return
+		}
+		w.Write([]byte(`{"status":"OK","results":[{"place_id":"partial","partial_match":true}]}`))
+	}))
+	defer srv.Close()
+
+	r := New(Options{
+		BaseURL:                  srv.URL + "/?",
+		RelaxOnZeroResults:       true,
+		OnPartialRetryComponents: Components{"country": "DE"},
+	})
+	defer r.Close()
+
+	resp, raw, err := r.GeocodeRawBytes(context.Background(), "Unter den Linden 1, Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("sent %d requests, want 1", got)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].PlaceId != "partial" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if !strings.Contains(string(raw), `"partial"`) {
+		t.Errorf("body %s doesn't belong to the response", raw)
+	}
+}
+
+//staticServer answers every request with body.
+func staticServer(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(body))
+	}))
+}
+
+func TestMaxURLLength(t *testing.T) {
+	srv := staticServer(testResponse)
+	defer srv.Close()
+	long := strings.Repeat("Unter den Linden 1, Berlin ", 10)
+
+	r := New(Options{BaseURL: srv.URL + "/?", MaxURLLength: 100})
+	defer r.Close()
+	if _, err := r.Geocode(long); !errors.Is(err, ErrURLTooLong) {
+		t.Errorf("GET: got %v, want ErrURLTooLong", err)
+	}
+
+	post := New(Options{BaseURL: srv.URL + "/?", MaxURLLength: 100, UsePOST: true})
+	defer post.Close()
+	if _, err := post.Geocode(long); err != nil {
+		t.Errorf("POST: got %v, want no error", err)
+	}
+}
+
+func TestUsePOST(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			t.Errorf("method %s, want POST", req.Method)
+		}
+		if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type %q, want application/json", ct)
+		}
+		if req.URL.RawQuery != "" {
+			t.Errorf("parameters leaked into the url: %q", req.URL.RawQuery)
+		}
+		params := map[string]string{}
+		if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+			t.Errorf("body is no json object: %v", err)
+		}
+		want := map[string]string{"address": "Unter den Linden 1, Berlin", "key": "secret", "language": "de"}
+		if !reflect.DeepEqual(params, want) {
+			t.Errorf("body %v, want %v", params, want)
+		}
+		w.Write([]byte(testResponse))
+	}))
+	defer srv.Close()
+
+	r := New(Options{BaseURL: srv.URL + "/geocode?", UsePOST: true, ApiKey: "secret", Lang: "de"})
+	defer r.Close()
+	if _, err := r.Geocode("Unter den Linden 1, Berlin"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUsePOSTGoogleHost(t *testing.T) {
+	for _, base := range []string{"", BASE_URL, "https://maps.google.cn/maps/api/geocode/json?"} {
+		r := New(Options{BaseURL: base, UsePOST: true})
+		_, err := r.Geocode("Berlin")
+		r.Close()
+		if !errors.Is(err, ErrPOSTNotAllowed) {
+			t.Errorf("base url %q: got %v, want ErrPOSTNotAllowed", base, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package geopardotel creates OpenTelemetry spans for the calls of a geopard request processor.
+
+package geopardotel
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+
+	"github.com/dbriemann/geopard"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//instrumentationName is the name of the tracer used for all spans.
+const instrumentationName = "github.com/dbriemann/geopard/geopardotel"
+
+//Span attributes set on every span.
+const (
+	AttrQueryHash   = attribute.Key("geopard.query_hash")
+	AttrStatus      = attribute.Key("geopard.status")
+	AttrResultCount = attribute.Key("geopard.result_count")
+	AttrLatencyMs   = attribute.Key("geopard.latency_ms")
+)
+
+//OnCallStart returns a hook for geopard.Options.OnCallStart that starts a
+//span for every geocoding call, as a child of the span found in the context
+//passed to the ...Context methods. The span's context is used for the call,
+//so an instrumented http transport continues the trace.
+//
+//The address or coordinates are not recorded in clear text but as a sha256
+//hash, which still allows correlating calls for the same query.
+//If tp is nil the global tracer provider is used.
+func OnCallStart(tp trace.TracerProvider) func(context.Context, string) (context.Context, func(geopard.RequestInfo)) {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	tracer := tp.Tracer(instrumentationName)
+
+	return func(ctx context.Context, rawURL string) (context.Context, func(geopard.RequestInfo)) {
+		ctx, span := tracer.Start(ctx, "geopard.geocode",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(AttrQueryHash.String(queryHash(rawURL))),
+		)
+		return ctx, func(info geopard.RequestInfo) {
+			span.SetAttributes(
+				AttrStatus.String(info.Status),
+				AttrResultCount.Int(info.Results),
+				AttrLatencyMs.Int64(info.Duration.Milliseconds()),
+			)
+			if info.Err != nil {
+				span.RecordError(info.Err)
+				span.SetStatus(codes.Error, info.Err.Error())
+			}
+			span.End()
+		}
+	}
+}
+
+//queryHash returns the hex encoded sha256 hash of the address, coordinates
+//or place id queried by a request url.
+func queryHash(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	query := q.Get("address")
+	if query == "" {
+		query = q.Get("latlng")
+	}
+	if query == "" {
+		query = q.Get("place_id")
+	}
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
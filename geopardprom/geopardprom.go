@@ -0,0 +1,64 @@
+// Package geopardprom exports the stats of a geopard request processor as Prometheus metrics.
+
+package geopardprom
+
+import (
+	"github.com/dbriemann/geopard"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//StatsSource is implemented by the geopard request processor.
+type StatsSource interface {
+	Stats() geopard.Stats
+}
+
+//Collector is a prometheus.Collector reading the stats of a request
+//processor on every scrape. It provides the metrics
+//
+//	geopard_requests_total         counter, requests sent including retries
+//	geopard_errors_total{status}   counter, failed requests by response status
+//	geopard_throttle_available     gauge, requests possible without waiting
+//	geopard_effective_rate         gauge, requests allowed per period
+type Collector struct {
+	source    StatsSource
+	requests  *prometheus.Desc
+	errors    *prometheus.Desc
+	available *prometheus.Desc
+	rate      *prometheus.Desc
+}
+
+//NewCollector creates a collector for the given request processor. The
+//metric names are prefixed with "geopard_".
+func NewCollector(source StatsSource) *Collector {
+	return &Collector{
+		source: source,
+		requests: prometheus.NewDesc("geopard_requests_total",
+			"Number of requests sent to the geocoding service, including retries.", nil, nil),
+		errors: prometheus.NewDesc("geopard_errors_total",
+			"Number of failed requests by response status.", []string{"status"}, nil),
+		available: prometheus.NewDesc("geopard_throttle_available",
+			"Number of requests that can be sent without waiting for the rate limiter.", nil, nil),
+		rate: prometheus.NewDesc("geopard_effective_rate",
+			"Number of requests the rate limiter currently allows per period.", nil, nil),
+	}
+}
+
+//Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.requests
+	ch <- c.errors
+	ch <- c.available
+	ch <- c.rate
+}
+
+//Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.source.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.requests, prometheus.CounterValue, float64(stats.Requests))
+	for status, n := range stats.ErrorsByStatus {
+		ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(n), status)
+	}
+	ch <- prometheus.MustNewConstMetric(c.available, prometheus.GaugeValue, float64(stats.ThrottleAvailable))
+	ch <- prometheus.MustNewConstMetric(c.rate, prometheus.GaugeValue, float64(stats.EffectiveRate))
+}
@@ -0,0 +1,165 @@
+// Package geopardtest provides a fake geocoding server for hermetic tests of code using geopard.
+
+package geopardtest
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dbriemann/geopard"
+)
+
+//Server is a fake Google geocoding service answering with canned
+//responses. Requests for unknown addresses or points are answered
+//with ZERO_RESULTS.
+type Server struct {
+	*httptest.Server
+
+	mu             sync.Mutex
+	addresses      map[string]geopard.GResponse
+	points         []point
+	overLimit      int
+	transportFails int
+	requests       int
+}
+
+type point struct {
+	lat, lng float64
+	response geopard.GResponse
+}
+
+//NewServer starts a fake geocoding server. It must be closed by calling
+//Close when it is no longer needed.
+func NewServer() *Server {
+	s := &Server{addresses: map[string]geopard.GResponse{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+//Options returns default options pointed at the server, to be passed to
+//geopard.New. Fields may be changed before use, except for BaseURL.
+func (s *Server) Options() geopard.Options {
+	return geopard.Options{
+		ApiKey:  "geopardtest",
+		BaseURL: s.URL + "/maps/api/geocode/json?",
+	}
+}
+
+//AddAddress makes the server answer geocoding requests for address with
+//resp. If the status of resp is empty it is set to OK.
+func (s *Server) AddAddress(address string, resp geopard.GResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addresses[address] = withStatus(resp)
+}
+
+//AddPoint makes the server answer reverse geocoding requests for the given
+//coordinates with resp. Coordinates match if they differ by less than 1e-6
+//degrees. If the status of resp is empty it is set to OK.
+func (s *Server) AddPoint(lat, lng float64, resp geopard.GResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.points = append(s.points, point{lat: lat, lng: lng, response: withStatus(resp)})
+}
+
+//OverQueryLimit makes the next n requests fail with OVER_QUERY_LIMIT.
+func (s *Server) OverQueryLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overLimit = n
+}
+
+//FailTransport makes the server drop the connection of the next n requests
+//in the middle of the response body, which the client sees as a network
+//error. Sending the status line first keeps net/http from silently
+//retrying the request on a reused keep-alive connection.
+func (s *Server) FailTransport(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transportFails = n
+}
+
+//Requests returns the number of requests the server received.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) handle(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	if s.transportFails > 0 {
+		s.transportFails--
+		s.mu.Unlock()
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, buf, err := hj.Hijack(); err == nil {
+				//announce more body than is sent
+				buf.WriteString("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 1024\r\n\r\n{\"status\":")
+				buf.Flush()
+				conn.Close()
+				return
+			}
+		}
+		http.Error(w, "transport failure", http.StatusBadGateway)
+		return
+	}
+	if s.overLimit > 0 {
+		s.overLimit--
+		s.mu.Unlock()
+		writeJSON(w, geopard.GResponse{Status: "OVER_QUERY_LIMIT"})
+		return
+	}
+	resp, ok := s.lookup(req)
+	s.mu.Unlock()
+
+	if !ok {
+		resp = geopard.GResponse{Status: "ZERO_RESULTS", Results: []geopard.GResult{}}
+	}
+	writeJSON(w, resp)
+}
+
+//lookup finds the canned response for a request. s.mu must be held.
+func (s *Server) lookup(req *http.Request) (geopard.GResponse, bool) {
+	q := req.URL.Query()
+	if address := q.Get("address"); address != "" {
+		resp, ok := s.addresses[address]
+		return resp, ok
+	}
+
+	coords := strings.Split(q.Get("latlng"), ",")
+	if len(coords) != 2 {
+		return geopard.GResponse{}, false
+	}
+	lat, err := strconv.ParseFloat(coords[0], 64)
+	if err != nil {
+		return geopard.GResponse{}, false
+	}
+	lng, err := strconv.ParseFloat(coords[1], 64)
+	if err != nil {
+		return geopard.GResponse{}, false
+	}
+	for _, p := range s.points {
+		if math.Abs(p.lat-lat) < 1e-6 && math.Abs(p.lng-lng) < 1e-6 {
+			return p.response, true
+		}
+	}
+	return geopard.GResponse{}, false
+}
+
+func withStatus(resp geopard.GResponse) geopard.GResponse {
+	if resp.Status == "" {
+		resp.Status = "OK"
+	}
+	return resp
+}
+
+func writeJSON(w http.ResponseWriter, resp geopard.GResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
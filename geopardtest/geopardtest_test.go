@@ -0,0 +1,67 @@
+package geopardtest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dbriemann/geopard"
+)
+
+func TestFailTransport(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddAddress("Berlin", geopard.GResponse{Results: []geopard.GResult{{PlaceId: "berlin"}}})
+	r := geopard.New(s.Options())
+	defer r.Close()
+
+	//the first call opens a keep-alive connection that the failure reuses
+	if _, err := r.Geocode("Berlin"); err != nil {
+		t.Fatal(err)
+	}
+	s.FailTransport(1)
+	if _, err := r.Geocode("Berlin"); err == nil {
+		t.Fatal("transport failure not reported")
+	} else if geopard.Classify(err) != geopard.Transient {
+		t.Errorf("got class %v for %v, want Transient", geopard.Classify(err), err)
+	}
+	if got := s.Requests(); got != 2 {
+		t.Errorf("server got %d requests, want 2", got)
+	}
+	resp, err := r.Geocode("Berlin")
+	if err != nil || resp.Results[0].PlaceId != "berlin" {
+		t.Errorf("after the failure: %+v, %v", resp, err)
+	}
+}
+
+func TestScriptedResponses(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddAddress("Berlin", geopard.GResponse{Results: []geopard.GResult{{PlaceId: "berlin"}}})
+	s.AddPoint(52.52, 13.405, geopard.GResponse{Results: []geopard.GResult{{PlaceId: "point"}}})
+	r := geopard.New(s.Options())
+	defer r.Close()
+
+	s.OverQueryLimit(2)
+	for i := 0; i < 2; i++ {
+		if _, err := r.Geocode("Berlin"); !errors.Is(err, geopard.ErrOverLimit) {
+			t.Fatalf("call %d: got %v, want ErrOverLimit", i, err)
+		}
+	}
+	resp, err := r.Geocode("Berlin")
+	if err != nil || resp.Status != "OK" || resp.Results[0].PlaceId != "berlin" {
+		t.Errorf("address: %+v, %v", resp, err)
+	}
+	resp, err = r.ReverseGeocode(52.52, 13.405)
+	if err != nil || resp.Results[0].PlaceId != "point" {
+		t.Errorf("point: %+v, %v", resp, err)
+	}
+	if _, err := r.Geocode("Atlantis"); !errors.Is(err, geopard.ErrZeroResults) {
+		t.Errorf("unknown address: got %v, want ErrZeroResults", err)
+	}
+	if _, err := r.ReverseGeocode(0, 0); !errors.Is(err, geopard.ErrZeroResults) {
+		t.Errorf("unknown point: got %v, want ErrZeroResults", err)
+	}
+	if got := s.Requests(); got != 6 {
+		t.Errorf("server got %d requests, want 6", got)
+	}
+}
@@ -0,0 +1,45 @@
+package geopard
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//jitter randomizes retry delays so clients failing at the same time don't
+//retry in lockstep. It is shared by all clones of a request processor.
+type jitter struct {
+	fraction float64
+	mu       sync.Mutex
+	rnd      *rand.Rand
+}
+
+//newJitter returns a jitter for the given fraction or nil if fraction
+//disables it. If rnd is nil a source seeded from crypto/rand is used.
+func newJitter(fraction float64, rnd *rand.Rand) *jitter {
+	if fraction <= 0 {
+		return nil
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	if rnd == nil {
+		var seed [8]byte
+		crand.Read(seed[:])
+		rnd = rand.New(rand.NewSource(int64(binary.LittleEndian.Uint64(seed[:]))))
+	}
+	return &jitter{fraction: fraction, rnd: rnd}
+}
+
+//apply scales d by a random factor in [1-fraction, 1+fraction).
+func (j *jitter) apply(d time.Duration) time.Duration {
+	if j == nil {
+		return d
+	}
+	j.mu.Lock()
+	f := j.rnd.Float64()
+	j.mu.Unlock()
+	return time.Duration(float64(d) * (1 + j.fraction*(2*f-1)))
+}
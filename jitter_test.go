@@ -0,0 +1,70 @@
+package geopard
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestJitterDelays(t *testing.T) {
+	srv := staticServer(`{"status":"UNKNOWN_ERROR","results":[]}`)
+	defer srv.Close()
+	backoff := ExponentialBackoff{Base: time.Millisecond, Max: 4 * time.Millisecond}
+
+	tests := []struct {
+		name     string
+		jitter   float64
+		fraction float64
+	}{
+		{"disabled", 0, 0},
+		{"fraction", 0.5, 0.5},
+		{"clamped", 2, 1},
+	}
+	for _, tt := range tests {
+		var delays []time.Duration
+		r := New(Options{
+			BaseURL:    srv.URL + "/?",
+			MaxRetries: 4,
+			Backoff:    backoff,
+			Jitter:     tt.jitter,
+			Rand:       rand.New(rand.NewSource(42)),
+			OnRetry:    func(attempt int, delay time.Duration, err error) { delays = append(delays, delay) },
+		})
+		if _, err := r.Geocode("Berlin"); err == nil {
+			t.Fatalf("%s: expected an error", tt.name)
+		}
+		r.Close()
+
+		//the same seed yields the same factors
+		twin := rand.New(rand.NewSource(42))
+		if len(delays) != 4 {
+			t.Fatalf("%s: got %d retries, want 4", tt.name, len(delays))
+		}
+		for i, got := range delays {
+			base := backoff.NextDelay(i + 1)
+			want := base
+			if tt.fraction > 0 {
+				want = time.Duration(float64(base) * (1 + tt.fraction*(2*twin.Float64()-1)))
+			}
+			if got != want {
+				t.Errorf("%s: retry %d slept %v, want %v", tt.name, i+1, got, want)
+			}
+			if lo, hi := time.Duration(float64(base)*(1-tt.fraction)), time.Duration(float64(base)*(1+tt.fraction)); got < lo || got > hi {
+				t.Errorf("%s: retry %d slept %v outside [%v, %v]", tt.name, i+1, got, lo, hi)
+			}
+		}
+	}
+}
+
+func TestJitterSeeded(t *testing.T) {
+	a := newJitter(0.2, rand.New(rand.NewSource(7)))
+	b := newJitter(0.2, rand.New(rand.NewSource(7)))
+	for i := 0; i < 10; i++ {
+		if da, db := a.apply(time.Second), b.apply(time.Second); da != db {
+			t.Fatalf("delay %d differs for the same seed: %v != %v", i, da, db)
+		}
+	}
+	if d := (*jitter)(nil).apply(time.Second); d != time.Second {
+		t.Errorf("nil jitter changed the delay to %v", d)
+	}
+}
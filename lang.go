@@ -0,0 +1,59 @@
+package geopard
+
+import "unicode"
+
+//scriptLanguages maps Unicode scripts to the language used for addresses
+//written in them. Latin is missing on purpose: it is shared by too many
+//languages to guess one.
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	lang   string
+}{
+	{unicode.Han, "zh"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "iw"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+//detectLanguage guesses the language of an address from the dominant
+//script of its letters. Han is ambiguous; it is taken as Japanese if any
+//kana occur and as Chinese otherwise. The second return value is false if
+//no language could be guessed, e.g. for addresses in Latin script.
+func detectLanguage(address string) (string, bool) {
+	counts := map[string]int{}
+	kana := false
+	latin := 0
+	for _, c := range address {
+		if unicode.Is(unicode.Latin, c) {
+			latin++
+			continue
+		}
+		for _, sl := range scriptLanguages {
+			if unicode.Is(sl.script, c) {
+				counts[sl.lang]++
+				if sl.lang == "ja" {
+					kana = true
+				}
+				break
+			}
+		}
+	}
+	if kana {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+
+	best, bestCount := "", latin
+	for _, sl := range scriptLanguages {
+		if n := counts[sl.lang]; n > bestCount {
+			best, bestCount = sl.lang, n
+		}
+	}
+	return best, best != ""
+}
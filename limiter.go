@@ -0,0 +1,352 @@
+package geopard
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+//agingStep is the time a waiting request needs to gain one priority level.
+//It keeps low priority requests from starving behind a constant stream of
+//high priority ones.
+const agingStep = time.Second
+
+//defaultMaxQueriesPerSec is the rate used if Options.MaxQueriesPerSec is
+//not set.
+const defaultMaxQueriesPerSec = 10
+
+//Limiter is a rate limiter that can be shared by several request processors
+//via Options.Limiter.
+type Limiter struct {
+	l *limiter
+}
+
+//NewLimiter creates a rate limiter configured by the MaxQueriesPerSec,
+//Buckets, Adaptive, OnRefill, MaxPending and BlockWhenFull fields of opts,
+//with the same defaults as New. The other fields are ignored. It must be
+//stopped by calling Close.
+func NewLimiter(opts Options) *Limiter {
+	max := opts.MaxQueriesPerSec
+	if max <= 0 {
+		max = defaultMaxQueriesPerSec
+	}
+	return &Limiter{l: newLimiter(max, opts)}
+}
+
+//Close stops the limiter. Requests waiting for it are released and later
+//requests of the processors using it are no longer limited. Close may be
+//called multiple times and always returns nil.
+func (l *Limiter) Close() error {
+	l.l.stop()
+	return nil
+}
+
+//limiter hands out request slots at a fixed rate. Waiting requests are
+//grouped in weighted buckets and served by priority within their bucket.
+//It is shared by all clones of a request processor.
+type limiter struct {
+	max      int
+	throttle chan int
+	quit     chan int
+	stopOnce sync.Once
+	ticker   *time.Ticker
+	onRefill func(time.Time)
+	now      func() time.Time
+	//notify wakes the dispatcher when a request starts waiting
+	notify chan struct{}
+	//pending bounds the requests inside wait, it is nil if unbounded
+	pending      chan struct{}
+	blockPending bool
+
+	//adaptive rate state and waiting requests, guarded by mu
+	adaptive bool
+	mu       sync.Mutex
+	rate     int
+	limited  bool
+	buckets  map[string]*bucket
+	//vnow is the virtual time of the last bucket served
+	vnow    float64
+	waiting int
+}
+
+//bucket is a share of the rate limit for a named workload. Buckets are
+//served by weighted fair queuing: each served request advances the bucket's
+//virtual time by 1/weight and the waiting bucket with the lowest virtual
+//time is served next. Idle buckets don't hold back slots, and a bucket that
+//becomes active again starts at the current virtual time so it can't claim
+//the share it didn't use while idle.
+type bucket struct {
+	weight  float64
+	vtime   float64
+	waiters waitQueue
+}
+
+//waiter is a request waiting for a slot.
+type waiter struct {
+	bucket *bucket
+	//score orders waiters, the highest score is served first
+	score int64
+	ready chan struct{}
+	//index is the position in the wait queue or -1 if not queued
+	index int
+}
+
+//waitQueue is a max-heap of waiters ordered by score.
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int           { return len(q) }
+func (q waitQueue) Less(i, j int) bool { return q[i].score > q[j].score }
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waitQueue) Push(x interface{}) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() interface{} {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*q = old[:len(old)-1]
+	return w
+}
+
+func newLimiter(max int, opts Options) *limiter {
+	l := &limiter{
+		max:      max,
+		throttle: make(chan int, max),
+		quit:     make(chan int),
+		onRefill: opts.OnRefill,
+		now:      time.Now,
+		notify:   make(chan struct{}, 1),
+		adaptive: opts.Adaptive,
+		rate:     max,
+		buckets:  map[string]*bucket{DefaultBucket: {weight: 1}},
+	}
+	if opts.MaxPending > 0 {
+		l.pending = make(chan struct{}, opts.MaxPending)
+		l.blockPending = opts.BlockWhenFull
+	}
+	for name, weight := range opts.Buckets {
+		if weight > 0 {
+			l.buckets[name] = &bucket{weight: float64(weight)}
+		}
+	}
+	//allow requests for first time so we don't have to wait for the ticker period
+	l.allowRequests()
+	l.ticker = time.NewTicker(5 * time.Second)
+	go l.multiTick()
+	go l.dispatch()
+	return l
+}
+
+//allowRequests tops up the free request slots to the current rate.
+func (l *limiter) allowRequests() {
+	l.mu.Lock()
+	if l.adaptive && !l.limited && l.rate < l.max {
+		l.rate++
+	}
+	l.limited = false
+	rate := l.rate
+	l.mu.Unlock()
+
+	for i := len(l.throttle) + 1; i <= rate; i++ {
+		select {
+		case l.throttle <- i:
+		default:
+			//all slots are free already
+			return
+		}
+	}
+}
+
+func (l *limiter) multiTick() {
+	for {
+		select {
+		case <-l.quit:
+			l.ticker.Stop()
+			return
+		case <-l.ticker.C:
+			l.allowRequests()
+			if l.onRefill != nil {
+				go l.onRefill(time.Now())
+			}
+		}
+	}
+}
+
+//wait blocks until a request slot is assigned or ctx is done. Requests
+//with a higher priority are served first. Every agingStep spent waiting
+//raises a request's priority by one, so eventually every request is served.
+func (l *limiter) wait(ctx context.Context, bucketName string, priority int) error {
+	if l.pending != nil {
+		if l.blockPending {
+			select {
+			case l.pending <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-l.quit:
+				return nil
+			}
+		} else {
+			select {
+			case l.pending <- struct{}{}:
+			default:
+				return ErrQueueFull
+			}
+		}
+		defer func() { <-l.pending }()
+	}
+
+	l.mu.Lock()
+	b, ok := l.buckets[bucketName]
+	if !ok {
+		l.mu.Unlock()
+		return ErrUnknownBucket
+	}
+	//the score grows with priority and shrinks with the time the wait
+	//started, which makes earlier requests gain on later ones
+	w := &waiter{
+		bucket: b,
+		score:  int64(priority)*int64(agingStep) - l.now().UnixNano(),
+		ready:  make(chan struct{}),
+	}
+	if len(b.waiters) == 0 && b.vtime < l.vnow {
+		b.vtime = l.vnow
+	}
+	heap.Push(&b.waiters, w)
+	l.waiting++
+	l.mu.Unlock()
+
+	select {
+	case l.notify <- struct{}{}:
+	default:
+		//the dispatcher has been notified already
+	}
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-l.quit:
+		//a stopped limiter doesn't limit anymore
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		queued := w.index >= 0
+		if queued {
+			heap.Remove(&b.waiters, w.index)
+			l.waiting--
+		}
+		l.mu.Unlock()
+		if !queued {
+			//the slot was assigned concurrently, hand it back
+			l.release()
+		}
+		return ctx.Err()
+	}
+}
+
+//dispatch assigns free slots to waiting requests.
+func (l *limiter) dispatch() {
+	for {
+		select {
+		case <-l.quit:
+			return
+		case <-l.notify:
+		}
+
+		for l.queued() > 0 {
+			select {
+			case <-l.quit:
+				return
+			case <-l.throttle:
+			}
+
+			l.mu.Lock()
+			b := l.nextBucket()
+			if b == nil {
+				//the waiters gave up in the meantime
+				l.mu.Unlock()
+				l.release()
+				break
+			}
+			w := heap.Pop(&b.waiters).(*waiter)
+			l.waiting--
+			l.vnow = b.vtime
+			b.vtime += 1 / b.weight
+			close(w.ready)
+			l.mu.Unlock()
+		}
+	}
+}
+
+//nextBucket returns the waiting bucket with the lowest virtual time or nil
+//if no request is waiting. l.mu must be held.
+func (l *limiter) nextBucket() *bucket {
+	var next *bucket
+	for _, b := range l.buckets {
+		if len(b.waiters) > 0 && (next == nil || b.vtime < next.vtime) {
+			next = b
+		}
+	}
+	return next
+}
+
+//queued returns the number of waiting requests.
+func (l *limiter) queued() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.waiting
+}
+
+//release returns an unused slot.
+func (l *limiter) release() {
+	select {
+	case l.throttle <- 0:
+	default:
+	}
+}
+
+//feedback adapts the rate to the outcome of a request if the limiter
+//is adaptive. Hitting the query limit halves the rate.
+func (l *limiter) feedback(err error) {
+	if !l.adaptive || !errors.Is(err, ErrOverLimit) {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limited = true
+	if l.rate > 1 {
+		l.rate /= 2
+	}
+	//drop slots exceeding the reduced rate
+	for len(l.throttle) > l.rate {
+		select {
+		case <-l.throttle:
+		default:
+			return
+		}
+	}
+}
+
+//currentRate returns the number of requests currently allowed per period.
+func (l *limiter) currentRate() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+//stop ends dispatching and refilling. It is safe to call multiple times.
+func (l *limiter) stop() {
+	l.stopOnce.Do(func() { close(l.quit) })
+}
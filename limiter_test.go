@@ -0,0 +1,212 @@
+package geopard
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+//drainLimiter takes all free slots of l so waiters block until the test
+//hands out slots itself.
+func drainLimiter(l *limiter) {
+	for len(l.throttle) > 0 {
+		<-l.throttle
+	}
+}
+
+//queueWaiter starts a request waiting in l that sends id to served once
+//it got a slot, and returns when the request is queued.
+func queueWaiter(t *testing.T, l *limiter, bucket string, priority int, id string, served chan<- string) {
+	t.Helper()
+	queued := l.queued()
+	go func() {
+		if err := l.wait(context.Background(), bucket, priority); err != nil {
+			t.Error(err)
+		}
+		served <- id
+	}()
+	for deadline := time.Now().Add(time.Second); l.queued() == queued; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s was not queued", id)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+//dispatchOrder hands out n slots one by one and returns the ids of the
+//requests served.
+func dispatchOrder(l *limiter, n int, served <-chan string) []string {
+	order := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		l.throttle <- 1
+		order = append(order, <-served)
+	}
+	return order
+}
+
+func TestAdaptiveRate(t *testing.T) {
+	l := newLimiter(8, Options{Adaptive: true})
+	defer l.stop()
+
+	//halved on every over limit error down to a floor of 1
+	for _, want := range []int{4, 2, 1, 1} {
+		l.feedback(ErrOverLimit)
+		if got := l.currentRate(); got != want {
+			t.Fatalf("after over limit: rate %d, want %d", got, want)
+		}
+		if len(l.throttle) > want {
+			t.Fatalf("%d free slots exceed the rate %d", len(l.throttle), want)
+		}
+	}
+
+	//other errors don't change the rate
+	l.feedback(ErrZeroResults)
+	l.feedback(nil)
+	if got := l.currentRate(); got != 1 {
+		t.Fatalf("rate changed to %d by other errors", got)
+	}
+
+	//the refill right after a limited period doesn't raise the rate
+	l.allowRequests()
+	if got := l.currentRate(); got != 1 {
+		t.Fatalf("rate %d after refill of a limited period, want 1", got)
+	}
+	//every following refill adds one up to the configured maximum
+	for _, want := range []int{2, 3, 4, 5, 6, 7, 8, 8} {
+		l.allowRequests()
+		if got := l.currentRate(); got != want {
+			t.Fatalf("rate %d after recovery, want %d", got, want)
+		}
+	}
+	if len(l.throttle) != 8 {
+		t.Errorf("%d free slots after recovery, want 8", len(l.throttle))
+	}
+}
+
+func TestAdaptiveRateDisabled(t *testing.T) {
+	l := newLimiter(8, Options{})
+	defer l.stop()
+	l.feedback(ErrOverLimit)
+	if got := l.currentRate(); got != 8 {
+		t.Errorf("rate %d of a non adaptive limiter, want 8", got)
+	}
+}
+
+func TestPriorityOrder(t *testing.T) {
+	l := newLimiter(10, Options{})
+	defer l.stop()
+	drainLimiter(l)
+	start := time.Unix(1700000000, 0)
+	l.now = func() time.Time { return start }
+
+	served := make(chan string)
+	queueWaiter(t, l, DefaultBucket, 0, "low", served)
+	queueWaiter(t, l, DefaultBucket, 5, "high", served)
+	queueWaiter(t, l, DefaultBucket, -1, "lowest", served)
+	queueWaiter(t, l, DefaultBucket, 2, "medium", served)
+
+	got := strings.Join(dispatchOrder(l, 4, served), " ")
+	if want := "high medium low lowest"; got != want {
+		t.Errorf("served %q, want %q", got, want)
+	}
+}
+
+func TestPriorityAging(t *testing.T) {
+	l := newLimiter(10, Options{})
+	defer l.stop()
+	drainLimiter(l)
+	start := time.Unix(1700000000, 0)
+	at := func(d time.Duration) { l.now = func() time.Time { return start.Add(d) } }
+
+	served := make(chan string)
+	at(0)
+	queueWaiter(t, l, DefaultBucket, 0, "old low", served)
+	//one level higher but only half an aging step later: served first
+	at(agingStep / 2)
+	queueWaiter(t, l, DefaultBucket, 1, "recent high", served)
+	//one level higher but two aging steps later: the old request aged past it
+	at(2 * agingStep)
+	queueWaiter(t, l, DefaultBucket, 1, "late high", served)
+
+	got := strings.Join(dispatchOrder(l, 3, served), ", ")
+	if want := "recent high, old low, late high"; got != want {
+		t.Errorf("served %q, want %q", got, want)
+	}
+}
+
+func TestBucketWeights(t *testing.T) {
+	l := newLimiter(10, Options{Buckets: map[string]int{"bulk": 1, "interactive": 2}})
+	defer l.stop()
+	drainLimiter(l)
+
+	served := make(chan string)
+	for i := 0; i < 6; i++ {
+		queueWaiter(t, l, "bulk", 0, "bulk", served)
+		queueWaiter(t, l, "interactive", 0, "interactive", served)
+	}
+
+	counts := map[string]int{}
+	for _, id := range dispatchOrder(l, 9, served) {
+		counts[id]++
+	}
+	if counts["interactive"] != 6 || counts["bulk"] != 3 {
+		t.Errorf("served %v, want interactive and bulk at 2:1", counts)
+	}
+	//the remaining bulk requests are served once interactive is idle
+	for _, id := range dispatchOrder(l, 3, served) {
+		if id != "bulk" {
+			t.Errorf("served %s, want bulk", id)
+		}
+	}
+}
+
+func TestMaxPending(t *testing.T) {
+	l := newLimiter(1, Options{MaxPending: 2})
+	defer l.stop()
+	drainLimiter(l)
+
+	served := make(chan string)
+	queueWaiter(t, l, DefaultBucket, 0, "a", served)
+	queueWaiter(t, l, DefaultBucket, 0, "b", served)
+	if err := l.wait(context.Background(), DefaultBucket, 0); err != ErrQueueFull {
+		t.Fatalf("full queue: got %v, want ErrQueueFull", err)
+	}
+
+	//a served request makes room for another one
+	dispatchOrder(l, 1, served)
+	queueWaiter(t, l, DefaultBucket, 0, "c", served)
+	if got := strings.Join(dispatchOrder(l, 2, served), ""); got != "bc" {
+		t.Errorf("served %s, want bc", got)
+	}
+}
+
+func TestMaxPendingBlock(t *testing.T) {
+	l := newLimiter(1, Options{MaxPending: 1, BlockWhenFull: true})
+	defer l.stop()
+	drainLimiter(l)
+
+	served := make(chan string)
+	queueWaiter(t, l, DefaultBucket, 0, "a", served)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx, DefaultBucket, 0); err != context.DeadlineExceeded {
+		t.Fatalf("blocked on full queue: got %v, want context.DeadlineExceeded", err)
+	}
+
+	//b blocks until a leaves the queue
+	go func() {
+		if err := l.wait(context.Background(), DefaultBucket, 0); err != nil {
+			t.Error(err)
+		}
+		served <- "b"
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if n := l.queued(); n != 1 {
+		t.Fatalf("%d requests queued, want 1", n)
+	}
+	if got := strings.Join(dispatchOrder(l, 2, served), ""); got != "ab" {
+		t.Errorf("served %s, want ab", got)
+	}
+}
@@ -0,0 +1,138 @@
+package geopard
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//LangErrors is returned by GeocodeMulti if the requests for some
+//languages failed. It maps each failed language code to its error.
+type LangErrors map[string]error
+
+func (e LangErrors) Error() string {
+	langs := make([]string, 0, len(e))
+	for lang := range e {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	msgs := make([]string, len(langs))
+	for i, lang := range langs {
+		msgs[i] = lang + ": " + e[lang].Error()
+	}
+	return "geocoding failed for " + strings.Join(msgs, "; ")
+}
+
+//GeocodeMulti geocodes the given address once for every language in langs.
+//The requests are sent concurrently but still obey the rate limit, so every
+//language consumes one request of the quota. The returned map contains the
+//responses of all successful requests keyed by language code. If any request
+//fails the error is of type LangErrors and holds the errors per language,
+//while the responses of the remaining languages are still returned.
+func (r *requestProcessor) GeocodeMulti(ctx context.Context, address string, langs []string, opts ...RequestOption) (map[string]GResponse, error) {
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		responses = make(map[string]GResponse, len(langs))
+		errs      = LangErrors{}
+	)
+
+	wg.Add(len(langs))
+	for _, lang := range langs {
+		go func(lang string) {
+			defer wg.Done()
+
+			//the language option is appended last so it wins over opts
+			resp, err := r.GeocodeContext(ctx, address, append(opts[:len(opts):len(opts)], WithLang(lang))...)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[lang] = err
+				return
+			}
+			responses[lang] = resp
+		}(lang)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return responses, errs
+	}
+	return responses, nil
+}
+
+//TransliteratedComponent is an address component in the local language
+//extended by its english, usually Latin script, names.
+type TransliteratedComponent struct {
+	GAddrComponent
+	LatinLong  string
+	LatinShort string
+}
+
+//TransliteratedResult is a result in the local language whose address
+//components carry the english names as well. Components without an english
+//counterpart have empty Latin names.
+type TransliteratedResult struct {
+	GResult
+	Components []TransliteratedComponent
+}
+
+//GeocodeWithTransliteration geocodes the address in localLang and in
+//english (via GeocodeMulti) and merges the address components of both
+//responses. Results are matched by place id and components by their types.
+//
+//Every call sends two requests, consuming two requests of the quota, and
+//both obey the rate limit. If either request fails its error is returned
+//as LangErrors.
+func (r *requestProcessor) GeocodeWithTransliteration(ctx context.Context, address, localLang string, opts ...RequestOption) ([]TransliteratedResult, error) {
+	responses, err := r.GeocodeMulti(ctx, address, []string{localLang, "en"}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	latin := make(map[PlaceID]GResult, len(responses["en"].Results))
+	for _, res := range responses["en"].Results {
+		latin[res.PlaceId] = res
+	}
+
+	local := responses[localLang].Results
+	results := make([]TransliteratedResult, len(local))
+	for i, res := range local {
+		results[i] = TransliteratedResult{GResult: res, Components: transliterate(res, latin[res.PlaceId])}
+	}
+	return results, nil
+}
+
+//transliterate pairs the components of a local result with the components
+//of the same types in the latin result.
+func transliterate(local, latin GResult) []TransliteratedComponent {
+	used := make([]bool, len(latin.AddrComponents))
+	components := make([]TransliteratedComponent, len(local.AddrComponents))
+	for i, c := range local.AddrComponents {
+		components[i].GAddrComponent = c
+		for j, lc := range latin.AddrComponents {
+			if !used[j] && sameTypes(c.Types, lc.Types) {
+				used[j] = true
+				components[i].LatinLong = lc.Long
+				components[i].LatinShort = lc.Short
+				break
+			}
+		}
+	}
+	return components
+}
+
+func sameTypes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
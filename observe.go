@@ -0,0 +1,97 @@
+package geopard
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+//RequestInfo describes a finished request to the geocoding service.
+//It is passed to the Options.OnResponse hook. The function returned by
+//Options.OnCallStart receives a RequestInfo describing the whole call
+//including cache lookups and retries.
+type RequestInfo struct {
+	//CorrelationID is the id returned by Options.CorrelationIDFromContext
+	//or empty if no extractor is configured.
+	CorrelationID string
+	//URL is the request url with the api key removed.
+	URL string
+	//Status is the status string returned by Google, e.g. "OK".
+	//It is empty if no response could be decoded.
+	Status string
+	//Results is the number of results in the response.
+	Results int
+	//Duration is the time the request took including the wait for the
+	//rate limiter.
+	Duration time.Duration
+	//WaitDuration is the part of Duration spent waiting for the rate
+	//limiter. It is zero for replayed requests and is not set for the
+	//RequestInfo describing a whole call (see Options.OnCallStart).
+	WaitDuration time.Duration
+	//Err is the error returned to the caller.
+	Err error
+}
+
+//observe reports a finished request to the logger and hooks.
+func (r *requestProcessor) observe(ctx context.Context, info RequestInfo) {
+	if r.correlationID != nil {
+		info.CorrelationID = r.correlationID(ctx)
+	}
+
+	if r.logger != nil {
+		attrs := []slog.Attr{
+			slog.String("url", info.URL),
+			slog.String("status", info.Status),
+			slog.Duration("duration", info.Duration),
+			slog.Duration("wait", info.WaitDuration),
+		}
+		if info.CorrelationID != "" {
+			attrs = append(attrs, slog.String("correlation_id", info.CorrelationID))
+		}
+		level := slog.LevelDebug
+		if info.Err != nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String("error", info.Err.Error()))
+		}
+		r.logger.LogAttrs(ctx, level, "geocoding request", attrs...)
+
+		if r.slowThreshold > 0 && info.Duration > r.slowThreshold {
+			r.logger.LogAttrs(ctx, slog.LevelWarn, "slow geocoding request",
+				slog.String("url", info.URL),
+				slog.Duration("duration", info.Duration),
+				slog.Duration("wait", info.WaitDuration),
+				slog.Duration("network", info.Duration-info.WaitDuration),
+			)
+		}
+	}
+
+	if r.onResponse != nil {
+		r.onResponse(info)
+	}
+}
+
+//sanitizeURL removes the api key from a request url so it can be
+//logged safely.
+func sanitizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	if _, ok := q["key"]; !ok {
+		return rawURL
+	}
+	q.Del("key")
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+//sanitizeError removes the api key from the url embedded in errors
+//returned by the http client.
+func sanitizeError(err error) error {
+	if uerr, ok := err.(*url.Error); ok {
+		uerr.URL = sanitizeURL(uerr.URL)
+	}
+	return err
+}
@@ -0,0 +1,21 @@
+package geopard
+
+import "context"
+
+//PlaceID uniquely identifies a place in the Google Places database. It is
+//a distinct type so place ids can't be confused with addresses.
+//See: https://developers.google.com/maps/documentation/places/web-service/place-id
+type PlaceID string
+
+//String returns the place id as plain string.
+func (p PlaceID) String() string {
+	return string(p)
+}
+
+//GeocodeByPlaceID returns the result for the place with the given id. It
+//works like GeocodeContext otherwise.
+func (r *requestProcessor) GeocodeByPlaceID(ctx context.Context, id PlaceID, opts ...RequestOption) (GResponse, error) {
+	req := r.newRequest(opts)
+	req.params.Set("place_id", id.String())
+	return r.processRequestContext(ctx, req)
+}
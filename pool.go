@@ -0,0 +1,29 @@
+package geopard
+
+import (
+	"bytes"
+	"sync"
+)
+
+//maxPooledBuffer is the capacity above which buffers are not returned to
+//the pool, so a single huge response doesn't pin its memory forever.
+const maxPooledBuffer = 1 << 20
+
+//bufPool holds the buffers response bodies are read into before decoding.
+//It saves an allocation per request in high volume batch jobs.
+var bufPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	bufPool.Put(buf)
+}
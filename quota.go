@@ -0,0 +1,70 @@
+package geopard
+
+import (
+	"sync"
+	"time"
+)
+
+//quota counts the requests sent per UTC day. It is shared by all clones
+//of a request processor.
+type quota struct {
+	limit   int
+	enforce bool
+	now     func() time.Time
+
+	mu   sync.Mutex
+	day  time.Time
+	used int
+}
+
+//newQuota returns a quota for the given daily limit or nil if limit
+//disables quota tracking.
+func newQuota(limit int, enforce bool) *quota {
+	if limit <= 0 {
+		return nil
+	}
+	return &quota{limit: limit, enforce: enforce, now: time.Now}
+}
+
+//resetIfNewDay starts counting from zero after UTC midnight. q.mu must be held.
+func (q *quota) resetIfNewDay() {
+	today := q.now().UTC().Truncate(24 * time.Hour)
+	if !today.Equal(q.day) {
+		q.day = today
+		q.used = 0
+	}
+}
+
+//take counts a request. It fails with ErrQuotaExceeded without counting
+//if the quota is enforced and used up.
+func (q *quota) take() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.resetIfNewDay()
+	if q.enforce && q.used >= q.limit {
+		return ErrQuotaExceeded
+	}
+	q.used++
+	return nil
+}
+
+func (q *quota) remaining() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.resetIfNewDay()
+	if q.used >= q.limit {
+		return 0
+	}
+	return q.limit - q.used
+}
+
+//RemainingQuota returns how many requests are left of Options.DailyQuota
+//for the current UTC day, or -1 if no daily quota is configured. The count
+//only covers requests sent by this processor and its clones; Google doesn't
+//report the actual remaining quota.
+func (r *requestProcessor) RemainingQuota() int {
+	if r.quota == nil {
+		return -1
+	}
+	return r.quota.remaining()
+}
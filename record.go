@@ -0,0 +1,43 @@
+package geopard
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+//recordFile returns the name of the file holding the recorded response
+//for url. The api key is stripped so recordings can be shared.
+func recordFile(dir, url string) string {
+	sum := sha256.Sum256([]byte(sanitizeURL(url)))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json")
+}
+
+//replay returns the recorded response body for url. If replaying is
+//disabled or no recording exists the body is nil, unless strict replaying
+//is enabled which results in ErrReplayMissing.
+func (r *requestProcessor) replay(url string) ([]byte, error) {
+	if r.replayDir == "" {
+		return nil, nil
+	}
+
+	body, err := os.ReadFile(recordFile(r.replayDir, url))
+	if errors.Is(err, fs.ErrNotExist) {
+		if r.replayStrict {
+			return nil, ErrReplayMissing
+		}
+		return nil, nil
+	}
+	return body, err
+}
+
+//record stores the response body for url if recording is enabled.
+func (r *requestProcessor) record(url string, body []byte) error {
+	if r.recordDir == "" {
+		return nil
+	}
+	return os.WriteFile(recordFile(r.recordDir, url), body, 0644)
+}
@@ -0,0 +1,42 @@
+package geopard
+
+import (
+	"regexp"
+	"strings"
+)
+
+//relaxAddress simplifies an address by removing its last token. Tokens
+//are separated by whitespace and commas, so "Main St 5, Apt 3" becomes
+//"Main St 5, Apt" and then "Main St 5". Separators left at the end are
+//trimmed. The only token of an address is never removed, in which case
+//the second return value is false.
+func relaxAddress(address string) (string, bool) {
+	isSep := func(c rune) bool { return c == ',' || c == ' ' || c == '\t' || c == '\n' }
+	trimmed := strings.TrimRightFunc(address, isSep)
+	i := strings.LastIndexFunc(trimmed, isSep)
+	if i < 0 {
+		return address, false
+	}
+	relaxed := strings.TrimRightFunc(trimmed[:i], isSep)
+	return relaxed, relaxed != ""
+}
+
+//zip4 matches a US ZIP+4 code, capturing the 5 digit ZIP.
+var zip4 = regexp.MustCompile(`\b(\d{5})-\d{4}\b`)
+
+//zip5Query returns the address and options of a query with every ZIP+4
+//code replaced by its 5 digit ZIP. The last return value is false if the
+//query contains no ZIP+4 code.
+func (r *requestProcessor) zip5Query(address string, opts []RequestOption) (string, []RequestOption, bool) {
+	found := false
+	if zip4.MatchString(address) {
+		address = zip4.ReplaceAllString(address, "$1")
+		found = true
+	}
+	if m := zip4.FindStringSubmatch(r.newRequest(opts).components["postal_code"]); m != nil {
+		//the component is appended last so it wins over opts
+		opts = append(opts[:len(opts):len(opts)], WithComponents(Components{"postal_code": m[1]}))
+		found = true
+	}
+	return address, opts, found
+}
@@ -0,0 +1,253 @@
+package geopard
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+//RequestOption customizes a single call to Geocode or ReverseGeocode.
+//Request options only apply to the call they are passed to.
+type RequestOption func(*request)
+
+//request collects the query parameters of a single call to the
+//geocoding service.
+type request struct {
+	base    string
+	params  url.Values
+	timeout time.Duration
+	bounds  *GArea
+	bias    *GPoint
+	//components are merged from all WithComponents options
+	components Components
+	bucket     string
+	priority   int
+	preferred  []string
+	noCache    bool
+	maxAge     time.Duration
+	//raw receives the response body, see GeocodeRawBytes
+	raw *[]byte
+}
+
+//newRequest creates a request with the processor defaults and applies
+//the given options on top.
+func (r *requestProcessor) newRequest(opts []RequestOption) *request {
+	req := &request{base: r.baseURL, params: url.Values{}}
+	req.params.Set("language", r.lang)
+	req.params.Set("key", r.apiKey)
+	for _, opt := range opts {
+		opt(req)
+	}
+	//explicit bounds take precedence over a location bias
+	if req.bounds == nil && req.bias != nil {
+		bounds := BoundingBox(*req.bias, r.biasRadius)
+		req.bounds = &bounds
+	}
+	if req.bounds != nil {
+		req.params.Set("bounds", r.formatArea(*req.bounds))
+	}
+	if len(req.components) > 0 {
+		req.params.Set("components", req.components.String())
+	}
+	return req
+}
+
+//validate checks that the request has exactly one primary locator, which
+//is one of address, latlng, place_id or, on its own, components. Components
+//may be combined with an address but not with latlng or place_id, which
+//Google doesn't filter by components. Empty parameters count as missing.
+func (req *request) validate() error {
+	locators := 0
+	for _, p := range []string{"address", "latlng", "place_id"} {
+		if req.params.Get(p) != "" {
+			locators++
+		}
+	}
+	components := req.params.Get("components") != ""
+	switch {
+	case locators > 1, locators == 0 && !components:
+		return ErrConflictingParams
+	case components && req.params.Get("address") == "" && locators == 1:
+		return ErrConflictingParams
+	}
+	return nil
+}
+
+//formatArea encodes an area as expected by the bounds parameter.
+func (r *requestProcessor) formatArea(a GArea) string {
+	return r.formatCoord(a.SouthWest.Lat) + "," + r.formatCoord(a.SouthWest.Lng) + "|" +
+		r.formatCoord(a.NorthEast.Lat) + "," + r.formatCoord(a.NorthEast.Lng)
+}
+
+//postProcess applies the client side options of the request to a
+//successful response.
+func (req *request) postProcess(response *GResponse) {
+	if len(req.preferred) > 0 {
+		response.preferTypes(req.preferred)
+	}
+}
+
+func (req *request) url() string {
+	return req.base + req.params.Encode()
+}
+
+//WithSessionToken groups the request under the given session token
+//so it is billed together with preceding Places Autocomplete requests.
+//See: https://developers.google.com/maps/documentation/places/web-service/session-tokens
+func WithSessionToken(token string) RequestOption {
+	return func(req *request) {
+		req.params.Set("sessiontoken", token)
+	}
+}
+
+//NewSessionToken returns a random (version 4) UUID suitable to be used
+//with WithSessionToken.
+func NewSessionToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+//WithLang overrides the processor's language (see Options.Lang) for
+//a single request.
+func WithLang(lang string) RequestOption {
+	return func(req *request) {
+		req.params.Set("language", lang)
+	}
+}
+
+//WithResultType restricts the results of a reverse geocoding request to the
+//given address types (e.g. "street_address", "locality") on the server side.
+//Google ignores this parameter for forward geocoding requests.
+//GResponse.FilterByType offers additional filtering on the client side.
+func WithResultType(types ...string) RequestOption {
+	return func(req *request) {
+		req.params.Set("result_type", strings.Join(types, "|"))
+	}
+}
+
+//WithTimeout limits the duration of a single request including the wait
+//for the rate limiter. It is applied on top of the deadline of the context
+//passed to the ...Context methods and the timeout of the http client, so
+//whichever expires first wins.
+func WithTimeout(d time.Duration) RequestOption {
+	return func(req *request) {
+		req.timeout = d
+	}
+}
+
+//WithBounds makes Google prefer (but not restrict to) results within
+//the given area.
+//See: https://developers.google.com/maps/documentation/geocoding/requests-geocoding#Viewports
+func WithBounds(a GArea) RequestOption {
+	return func(req *request) {
+		req.bounds = &a
+	}
+}
+
+//WithLocationBias makes Google prefer results near the given point. The
+//geocoding api only supports rectangular bounds, so the point is converted
+//to the BoundingBox with a radius of Options.BiasRadius (5km by default)
+//around it. Bounds set via WithBounds take precedence.
+func WithLocationBias(p GPoint) RequestOption {
+	return func(req *request) {
+		req.bias = &p
+	}
+}
+
+//Components restricts results to those matching address components, mapping
+//a component type like "country" or "postal_code" to its required value.
+//See: https://developers.google.com/maps/documentation/geocoding/requests-geocoding#component-filtering
+type Components map[string]string
+
+//String encodes the components as expected by the components parameter,
+//sorted by component type, e.g. "country:DE|postal_code:10117".
+func (c Components) String() string {
+	types := make([]string, 0, len(c))
+	for t := range c {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = t + ":" + c[t]
+	}
+	return strings.Join(parts, "|")
+}
+
+//WithComponents adds a components filter to the request. If given multiple
+//times the components are merged, with later values replacing earlier ones
+//of the same type.
+func WithComponents(c Components) RequestOption {
+	return func(req *request) {
+		if req.components == nil {
+			req.components = Components{}
+		}
+		for t, v := range c {
+			req.components[t] = v
+		}
+	}
+}
+
+//DefaultBucket is the rate limit bucket of requests without WithBucket.
+const DefaultBucket = ""
+
+//WithBucket assigns the request to a named bucket of the rate limit
+//configured via Options.Buckets. Requests for unknown buckets fail with
+//ErrUnknownBucket.
+func WithBucket(name string) RequestOption {
+	return func(req *request) {
+		req.bucket = name
+	}
+}
+
+//WithPriority sets the priority of the request when waiting for the rate
+//limiter. Requests with higher priority are sent first, the default is 0.
+//Priorities only order requests within the same bucket (see WithBucket).
+//Waiting requests gain one priority level per second, so low priority
+//requests are delayed but never starved.
+func WithPriority(priority int) RequestOption {
+	return func(req *request) {
+		req.priority = priority
+	}
+}
+
+//PreferTypes reorders the results so that results having one of the given
+//types come first, in the order the types are given. For example
+//PreferTypes("premise", "route") moves premises to the front, followed by
+//routes and then all other results. This is a stable reorder on the client
+//side: no result is dropped and results of equal rank keep Google's order.
+func PreferTypes(types ...string) RequestOption {
+	return func(req *request) {
+		req.preferred = types
+	}
+}
+
+//WithNoCache makes the request skip the cached response (see
+//Options.CacheTTL) and always ask the geocoding service. The fresh response
+//still replaces the cached one. Like any uncached request it waits for the
+//rate limiter and consumes quota.
+func WithNoCache() RequestOption {
+	return func(req *request) {
+		req.noCache = true
+	}
+}
+
+//WithMaxAge makes the request treat a cached response older than d as
+//stale, even if it is within Options.CacheTTL, and ask the geocoding
+//service instead. The fresh response replaces the cached one. Other calls
+//are not affected. WithNoCache takes precedence, skipping the cache
+//regardless of the age.
+func WithMaxAge(d time.Duration) RequestOption {
+	return func(req *request) {
+		req.maxAge = d
+	}
+}
@@ -0,0 +1,32 @@
+package geopard
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestRequestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		params url.Values
+		want   error
+	}{
+		{"address", url.Values{"address": {"Berlin"}}, nil},
+		{"latlng", url.Values{"latlng": {"52.5,13.4"}}, nil},
+		{"place_id", url.Values{"place_id": {"p1"}}, nil},
+		{"components", url.Values{"components": {"country:DE"}}, nil},
+		{"address with components", url.Values{"address": {"Berlin"}, "components": {"country:DE"}}, nil},
+		{"none", url.Values{}, ErrConflictingParams},
+		{"address and latlng", url.Values{"address": {"Berlin"}, "latlng": {"52.5,13.4"}}, ErrConflictingParams},
+		{"address and place_id", url.Values{"address": {"Berlin"}, "place_id": {"p1"}}, ErrConflictingParams},
+		{"latlng and place_id", url.Values{"latlng": {"52.5,13.4"}, "place_id": {"p1"}}, ErrConflictingParams},
+		{"latlng with components", url.Values{"latlng": {"52.5,13.4"}, "components": {"country:DE"}}, ErrConflictingParams},
+		{"place_id with components", url.Values{"place_id": {"p1"}, "components": {"country:DE"}}, ErrConflictingParams},
+	}
+	for _, tt := range tests {
+		req := &request{params: tt.params}
+		if err := req.validate(); err != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
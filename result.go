@@ -0,0 +1,418 @@
+package geopard
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"sort"
+	"strconv"
+)
+
+//Location types as returned in GGeometry.LocationType, ordered from the
+//most to the least precise.
+const (
+	LocationRooftop           = "ROOFTOP"
+	LocationRangeInterpolated = "RANGE_INTERPOLATED"
+	LocationGeometricCenter   = "GEOMETRIC_CENTER"
+	LocationApproximate       = "APPROXIMATE"
+)
+
+//locationTypeRank maps a location type to a number that is higher the more
+//precise the location type is. Unknown location types rank lowest.
+func locationTypeRank(locationType string) int {
+	switch locationType {
+	case LocationRooftop:
+		return 4
+	case LocationRangeInterpolated:
+		return 3
+	case LocationGeometricCenter:
+		return 2
+	case LocationApproximate:
+		return 1
+	}
+	return 0
+}
+
+//ComponentMap returns all address components of the result as a flat map
+//from component type (e.g. "locality") to the component's long name.
+//Components usually carry several types and every type becomes a key.
+//If a type appears in more than one component, the last one wins.
+func (r GResult) ComponentMap() map[string]string {
+	return r.componentMap(func(c GAddrComponent) string { return c.Long })
+}
+
+//ShortComponentMap works like ComponentMap but maps each component type
+//to the component's short name (e.g. "US" instead of "United States").
+func (r GResult) ShortComponentMap() map[string]string {
+	return r.componentMap(func(c GAddrComponent) string { return c.Short })
+}
+
+func (r GResult) componentMap(name func(GAddrComponent) string) map[string]string {
+	m := make(map[string]string, len(r.AddrComponents))
+	for _, c := range r.AddrComponents {
+		for _, t := range c.Types {
+			m[t] = name(c)
+		}
+	}
+	return m
+}
+
+//CoveringArea returns the smallest area that contains the locations of all
+//results in the response. The second return value is false if the response
+//has no results.
+func (r GResponse) CoveringArea() (GArea, bool) {
+	if len(r.Results) == 0 {
+		return GArea{}, false
+	}
+
+	first := r.Results[0].Geometry.Location
+	area := GArea{NorthEast: first, SouthWest: first}
+	for _, res := range r.Results[1:] {
+		loc := res.Geometry.Location
+		area.NorthEast.Lat = math.Max(area.NorthEast.Lat, loc.Lat)
+		area.NorthEast.Lng = math.Max(area.NorthEast.Lng, loc.Lng)
+		area.SouthWest.Lat = math.Min(area.SouthWest.Lat, loc.Lat)
+		area.SouthWest.Lng = math.Min(area.SouthWest.Lng, loc.Lng)
+	}
+	return area, true
+}
+
+//FilterByType returns a copy of the response that only contains the results
+//having at least one of the given types. The original response is unchanged.
+func (r GResponse) FilterByType(types ...string) GResponse {
+	filtered := r
+	filtered.Results = make([]GResult, 0, len(r.Results))
+	for _, res := range r.Results {
+		if res.HasAnyType(types...) {
+			filtered.Results = append(filtered.Results, res)
+		}
+	}
+	return filtered
+}
+
+//HasAnyType reports whether the result has at least one of the given types.
+func (r GResult) HasAnyType(types ...string) bool {
+	for _, t := range r.Types {
+		for _, want := range types {
+			if t == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//MatchQuality is a coarse rating of how well a result matches the query.
+type MatchQuality int
+
+const (
+	Poor MatchQuality = iota
+	Approximate
+	Good
+	Exact
+)
+
+func (q MatchQuality) String() string {
+	switch q {
+	case Exact:
+		return "exact"
+	case Good:
+		return "good"
+	case Approximate:
+		return "approximate"
+	}
+	return "poor"
+}
+
+//Quality rates the result by combining its location type and whether it
+//is a partial match:
+//
+//	location type        full match    partial match
+//	ROOFTOP              Exact         Good
+//	RANGE_INTERPOLATED   Good          Approximate
+//	GEOMETRIC_CENTER     Approximate   Poor
+//	APPROXIMATE/unknown  Poor          Poor
+func (r GResult) Quality() MatchQuality {
+	rank := locationTypeRank(r.Geometry.LocationType) - 1
+	if r.PartialMatch {
+		rank--
+	}
+	if rank < int(Poor) {
+		return Poor
+	}
+	return MatchQuality(rank)
+}
+
+//WithinPolygon returns a copy of the response that only contains the results
+//whose location lies inside the given polygon. Polygons with fewer than 3
+//points don't enclose an area; for those the response is returned unchanged.
+//The original response is unchanged.
+func (r GResponse) WithinPolygon(poly []GPoint) GResponse {
+	if len(poly) < 3 {
+		return r
+	}
+
+	filtered := r
+	filtered.Results = make([]GResult, 0, len(r.Results))
+	for _, res := range r.Results {
+		if res.Geometry.Location.inPolygon(poly) {
+			filtered.Results = append(filtered.Results, res)
+		}
+	}
+	return filtered
+}
+
+//UniqueByPlaceID returns a copy of the response without results whose place
+//id already occurred in an earlier result. The first occurrence is kept and
+//the order of the remaining results is preserved. Results without a place id
+//are always kept. The original response is unchanged.
+func (r GResponse) UniqueByPlaceID() GResponse {
+	seen := make(map[PlaceID]bool, len(r.Results))
+	unique := r
+	unique.Results = make([]GResult, 0, len(r.Results))
+	for _, res := range r.Results {
+		if res.PlaceId != "" {
+			if seen[res.PlaceId] {
+				continue
+			}
+			seen[res.PlaceId] = true
+		}
+		unique.Results = append(unique.Results, res)
+	}
+	return unique
+}
+
+//allPartial reports whether the response has results and all of them are
+//partial matches.
+func (r GResponse) allPartial() bool {
+	for _, res := range r.Results {
+		if !res.PartialMatch {
+			return false
+		}
+	}
+	return len(r.Results) > 0
+}
+
+//preferTypes stable sorts the results by the position of their first type
+//found in types. Results with none of the types go last.
+func (r *GResponse) preferTypes(types []string) {
+	rank := func(res GResult) int {
+		for i, t := range types {
+			if res.HasAnyType(t) {
+				return i
+			}
+		}
+		return len(types)
+	}
+	sort.SliceStable(r.Results, func(i, j int) bool {
+		return rank(r.Results[i]) < rank(r.Results[j])
+	})
+}
+
+//SpansMultipleCountries reports whether the results lie in more than one
+//country, judged by the short name (ISO code) of their country component.
+//Results without a country component are ignored.
+func (r GResponse) SpansMultipleCountries() bool {
+	first := ""
+	for _, res := range r.Results {
+		c, ok := res.component("country")
+		if !ok {
+			continue
+		}
+		if first == "" {
+			first = c.Short
+		} else if c.Short != first {
+			return true
+		}
+	}
+	return false
+}
+
+//Points returns the locations of all results in order. The slice is empty,
+//but not nil, if there are no results.
+func (r GResponse) Points() []GPoint {
+	points := make([]GPoint, len(r.Results))
+	for i, res := range r.Results {
+		points[i] = res.Geometry.Location
+	}
+	return points
+}
+
+//ByPrecision returns a copy of the results sorted by the precision of their
+//location type, ROOFTOP first (see the Location... constants). Results with
+//the same location type keep their order. The response is unchanged.
+func (r GResponse) ByPrecision() []GResult {
+	sorted := make([]GResult, len(r.Results))
+	copy(sorted, r.Results)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return locationTypeRank(sorted[i].Geometry.LocationType) > locationTypeRank(sorted[j].Geometry.LocationType)
+	})
+	return sorted
+}
+
+//LocationInViewport reports whether the location of the result lies inside
+//its viewport. Results violating this are usually caused by data issues at
+//Google and can be flagged during imports.
+func (r GResult) LocationInViewport() bool {
+	return r.Geometry.Viewport.Contains(r.Geometry.Location)
+}
+
+//SameLocation reports whether a and b refer to the same location. Results
+//with the same non-empty place id always do. Otherwise, including when the
+//place ids differ, their locations must be at most toleranceMeters apart,
+//which catches the same place returned with slightly different coordinates.
+func (a GResult) SameLocation(b GResult, toleranceMeters float64) bool {
+	if a.PlaceId != "" && a.PlaceId == b.PlaceId {
+		return true
+	}
+	return a.Geometry.Location.DistanceTo(b.Geometry.Location) <= toleranceMeters
+}
+
+//BestNear returns the result that best balances precision and proximity
+//to ref. Every result is scored by
+//
+//	score = w * rank/4 + (1-w) * 1/(1 + d/1000)
+//
+//where rank is the precision of the location type from 4 (ROOFTOP) to 0
+//(unknown), d the distance to ref in meters and w the precisionWeight
+//clamped to [0, 1]. A weight of 1 picks the most precise result, 0 the
+//nearest one. The first of equally scored results wins. The second return
+//value is false if the response has no results.
+func (r GResponse) BestNear(ref GPoint, precisionWeight float64) (GResult, bool) {
+	if len(r.Results) == 0 {
+		return GResult{}, false
+	}
+	w := math.Max(0, math.Min(1, precisionWeight))
+	score := func(res GResult) float64 {
+		precision := float64(locationTypeRank(res.Geometry.LocationType)) / 4
+		proximity := 1 / (1 + res.Geometry.Location.DistanceTo(ref)/1000)
+		return w*precision + (1-w)*proximity
+	}
+
+	best, bestScore := 0, score(r.Results[0])
+	for i, res := range r.Results[1:] {
+		if s := score(res); s > bestScore {
+			best, bestScore = i+1, s
+		}
+	}
+	return r.Results[best], true
+}
+
+//PostalCodes returns the distinct postal codes of all results, taken from
+//their postal_code components, sorted ascending. The localities covered by
+//postal code results are returned by PostcodeLocalities.
+func (r GResponse) PostalCodes() []string {
+	seen := map[string]bool{}
+	codes := []string{}
+	for _, res := range r.Results {
+		if c, ok := res.component("postal_code"); ok && !seen[c.Long] {
+			seen[c.Long] = true
+			codes = append(codes, c.Long)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+//PostcodeLocalities returns the distinct localities listed in the
+//postcode_localities of all results, sorted ascending. Google only sets
+//them for postal code results spanning several localities.
+func (r GResponse) PostcodeLocalities() []string {
+	seen := map[string]bool{}
+	localities := []string{}
+	for _, res := range r.Results {
+		for _, l := range res.PostcodeLocalities {
+			if !seen[l] {
+				seen[l] = true
+				localities = append(localities, l)
+			}
+		}
+	}
+	sort.Strings(localities)
+	return localities
+}
+
+//MergeResponses combines several responses, e.g. of the same query sent to
+//different regions. The results are concatenated in the order given and
+//results whose place id occurred before are dropped, like UniqueByPlaceID
+//does. The status is OK if any response has status OK and the status of
+//the first response otherwise.
+func MergeResponses(responses ...GResponse) GResponse {
+	merged := GResponse{Results: []GResult{}}
+	for i, resp := range responses {
+		if i == 0 || resp.Status == "OK" {
+			if merged.Status != "OK" {
+				merged.Status = resp.Status
+			}
+		}
+		merged.Results = append(merged.Results, resp.Results...)
+	}
+	return merged.UniqueByPlaceID()
+}
+
+//establishmentTypes are the result types marking a business or point of
+//interest rather than a plain address.
+var establishmentTypes = []string{"establishment", "point_of_interest"}
+
+//IsEstablishment reports whether the result is a business or point of
+//interest, i.e. has type establishment or point_of_interest.
+func (r GResult) IsEstablishment() bool {
+	return r.HasAnyType(establishmentTypes...)
+}
+
+//Establishments returns a copy of the response that only contains the
+//results for which IsEstablishment holds. The original response is
+//unchanged.
+func (r GResponse) Establishments() GResponse {
+	return r.FilterByType(establishmentTypes...)
+}
+
+//Hash returns a stable id for the result, e.g. as database key. It is the
+//hex encoded sha256 hash of "place_id:" followed by the place id. Results
+//without a place id are hashed as "location:" followed by the latitude and
+//longitude rounded to 6 decimals (about 10cm), separated by a comma, and
+//"|" and the formatted address.
+func (r GResult) Hash() string {
+	key := "place_id:" + r.PlaceId.String()
+	if r.PlaceId == "" {
+		loc := r.Geometry.Location
+		key = "location:" + strconv.FormatFloat(loc.Lat, 'f', 6, 64) + "," +
+			strconv.FormatFloat(loc.Lng, 'f', 6, 64) + "|" + r.FormattedAddr
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+//GroupByType groups the results by their primary type, which is the first
+//element of Types as returned by Google. Every result appears under exactly
+//one key; results without types are grouped under "". Within a group the
+//results keep their order.
+func (r GResponse) GroupByType() map[string][]GResult {
+	groups := map[string][]GResult{}
+	for _, res := range r.Results {
+		primary := ""
+		if len(res.Types) > 0 {
+			primary = res.Types[0]
+		}
+		groups[primary] = append(groups[primary], res)
+	}
+	return groups
+}
+
+//HasStreetNumber reports whether the result has a street_number component,
+//i.e. resolved to a specific building rather than a street or area.
+func (r GResult) HasStreetNumber() bool {
+	_, ok := r.component("street_number")
+	return ok
+}
+
+//FirstWithStreetNumber returns the first result having a street number.
+//The second return value is false if there is none.
+func (r GResponse) FirstWithStreetNumber() (GResult, bool) {
+	for _, res := range r.Results {
+		if res.HasStreetNumber() {
+			return res, true
+		}
+	}
+	return GResult{}, false
+}
@@ -0,0 +1,51 @@
+package geopard
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuality(t *testing.T) {
+	tests := []struct {
+		locationType string
+		full         MatchQuality
+		partial      MatchQuality
+	}{
+		{LocationRooftop, Exact, Good},
+		{LocationRangeInterpolated, Good, Approximate},
+		{LocationGeometricCenter, Approximate, Poor},
+		{LocationApproximate, Poor, Poor},
+		{"", Poor, Poor},
+		{"SOMETHING_NEW", Poor, Poor},
+	}
+	for _, tt := range tests {
+		res := GResult{}
+		res.Geometry.LocationType = tt.locationType
+		if got := res.Quality(); got != tt.full {
+			t.Errorf("%q full match: got %v, want %v", tt.locationType, got, tt.full)
+		}
+		res.PartialMatch = true
+		if got := res.Quality(); got != tt.partial {
+			t.Errorf("%q partial match: got %v, want %v", tt.locationType, got, tt.partial)
+		}
+	}
+}
+
+func TestPostcodeLocalities(t *testing.T) {
+	resp := GResponse{Results: []GResult{
+		{PostcodeLocalities: []string{"Kleinmachnow", "Berlin"}, AddrComponents: []GAddrComponent{{"14532", "14532", []string{"postal_code"}}}},
+		{PostcodeLocalities: []string{"Stahnsdorf", "Berlin"}},
+		{AddrComponents: []GAddrComponent{{"10117", "10117", []string{"postal_code"}}}},
+	}}
+	got := resp.PostcodeLocalities()
+	want := []string{"Berlin", "Kleinmachnow", "Stahnsdorf"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("PostcodeLocalities() = %q, want %q", got, want)
+	}
+	if got := resp.PostalCodes(); strings.Join(got, "|") != "10117|14532" {
+		t.Errorf("PostalCodes() = %q, want [10117 14532]", got)
+	}
+	if got := (GResponse{}).PostcodeLocalities(); got == nil || len(got) != 0 {
+		t.Errorf("empty response: got %#v, want an empty slice", got)
+	}
+}
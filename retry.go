@@ -0,0 +1,104 @@
+package geopard
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//Backoff calculates the delay before a retry. Attempt is 1 for the
+//first retry, 2 for the second one and so on.
+type Backoff interface {
+	NextDelay(attempt int) time.Duration
+}
+
+//ExponentialBackoff doubles the delay with every attempt, starting at Base.
+//The delay never exceeds Max unless Max is zero.
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func (b ExponentialBackoff) NextDelay(attempt int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempt && delay < math.MaxInt64/2; i++ {
+		delay *= 2
+		if b.Max > 0 && delay >= b.Max {
+			break
+		}
+	}
+	if b.Max > 0 && delay > b.Max {
+		delay = b.Max
+	}
+	return delay
+}
+
+//ConstantBackoff waits the same Delay before every attempt.
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+func (b ConstantBackoff) NextDelay(attempt int) time.Duration {
+	return b.Delay
+}
+
+//sleep waits for the given duration or until ctx is done, in which case
+//the context's error is returned.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//maxRetryAfter caps the delay requested by a Retry-After header unless the
+//backoff is an ExponentialBackoff with a Max.
+const maxRetryAfter = time.Minute
+
+//capRetryAfter limits the delay requested by a Retry-After header to the
+//Max of b if it is an ExponentialBackoff with a Max, or maxRetryAfter.
+func capRetryAfter(d time.Duration, b Backoff) time.Duration {
+	limit := maxRetryAfter
+	switch eb := b.(type) {
+	case ExponentialBackoff:
+		if eb.Max > 0 {
+			limit = eb.Max
+		}
+	case *ExponentialBackoff:
+		if eb != nil && eb.Max > 0 {
+			limit = eb.Max
+		}
+	}
+	if d > limit {
+		return limit
+	}
+	return d
+}
+
+//parseRetryAfter returns the delay requested by a Retry-After header, which
+//is either a number of seconds or an http date. It returns zero if the
+//header is empty, malformed or lies in the past.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
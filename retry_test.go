@@ -0,0 +1,43 @@
+package geopard
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCapRetryAfter(t *testing.T) {
+	tests := []struct {
+		delay   time.Duration
+		backoff Backoff
+		want    time.Duration
+	}{
+		{time.Second, ExponentialBackoff{Base: time.Millisecond, Max: 5 * time.Second}, time.Second},
+		{time.Hour, ExponentialBackoff{Base: time.Millisecond, Max: 5 * time.Second}, 5 * time.Second},
+		{time.Hour, &ExponentialBackoff{Base: time.Millisecond, Max: 2 * time.Second}, 2 * time.Second},
+		{time.Hour, ExponentialBackoff{Base: time.Millisecond}, maxRetryAfter},
+		{time.Hour, ConstantBackoff{Delay: time.Second}, maxRetryAfter},
+		{30 * time.Second, ConstantBackoff{Delay: time.Second}, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := capRetryAfter(tt.delay, tt.backoff); got != tt.want {
+			t.Errorf("capRetryAfter(%v, %+v) = %v, want %v", tt.delay, tt.backoff, got, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"-1":                            0,
+		"soon":                          0,
+		"Mon, 01 Jan 2024 12:00:30 GMT": 30 * time.Second,
+		"Mon, 01 Jan 2024 11:00:00 GMT": 0,
+	}
+	for header, want := range tests {
+		if got := parseRetryAfter(header, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package geopard
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+//StatusNetworkError is the key in Stats.ErrorsByStatus for failed requests
+//that didn't yield a decodable response.
+const StatusNetworkError = "NETWORK_ERROR"
+
+//Stats is a snapshot of the request processor's counters.
+type Stats struct {
+	//Requests is the number of requests sent, including retries.
+	Requests uint64
+	//Errors is the number of requests that failed.
+	Errors uint64
+	//ErrorsByStatus breaks down Errors by the status of the response, e.g.
+	//"OVER_QUERY_LIMIT", or StatusNetworkError if there was no response.
+	ErrorsByStatus map[string]uint64
+	//EffectiveRate is the number of requests the rate limiter currently
+	//allows per period. It only differs from MaxQueriesPerSec if
+	//Options.Adaptive is set.
+	EffectiveRate int
+	//ThrottleAvailable is the number of requests that can be sent right
+	//now without waiting for the rate limiter.
+	ThrottleAvailable int
+}
+
+//stats holds the counters of a request processor. It is shared by all
+//clones of a request processor.
+type stats struct {
+	requests atomic.Uint64
+	errors   atomic.Uint64
+
+	mu       sync.Mutex
+	byStatus map[string]uint64
+}
+
+func (s *stats) count(status string, err error) {
+	s.requests.Add(1)
+	if err == nil {
+		return
+	}
+	s.errors.Add(1)
+
+	if status == "" {
+		status = StatusNetworkError
+	}
+	s.mu.Lock()
+	if s.byStatus == nil {
+		s.byStatus = map[string]uint64{}
+	}
+	s.byStatus[status]++
+	s.mu.Unlock()
+}
+
+//Stats returns a snapshot of the request processor's counters.
+func (r *requestProcessor) Stats() Stats {
+	r.stats.mu.Lock()
+	byStatus := make(map[string]uint64, len(r.stats.byStatus))
+	for status, n := range r.stats.byStatus {
+		byStatus[status] = n
+	}
+	r.stats.mu.Unlock()
+
+	return Stats{
+		Requests:          r.stats.requests.Load(),
+		Errors:            r.stats.errors.Load(),
+		ErrorsByStatus:    byStatus,
+		EffectiveRate:     r.limiter.currentRate(),
+		ThrottleAvailable: len(r.limiter.throttle),
+	}
+}
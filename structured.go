@@ -0,0 +1,52 @@
+package geopard
+
+import "context"
+
+//StructuredAddress is an address split into fields, as provided by many
+//data sources. It is geocoded by GeocodeStructured, which maps the fields
+//as follows:
+//
+//	field        sent as
+//	Line1        address (required)
+//	Line2        address, appended to Line1
+//	City         components locality
+//	PostalCode   components postal_code
+//	Country      components country (name or ISO 3166-1 code)
+//
+//Filtering by components instead of concatenating all fields into the
+//address gives more accurate results.
+type StructuredAddress struct {
+	Line1      string
+	Line2      string
+	City       string
+	PostalCode string
+	Country    string
+}
+
+//components returns the component filter for the fields not sent as
+//part of the address.
+func (a StructuredAddress) components() Components {
+	c := Components{}
+	if a.City != "" {
+		c["locality"] = a.City
+	}
+	if a.PostalCode != "" {
+		c["postal_code"] = a.PostalCode
+	}
+	if a.Country != "" {
+		c["country"] = a.Country
+	}
+	return c
+}
+
+//GeocodeStructured geocodes a structured address, see StructuredAddress.
+//It returns ErrEmptyAddress without sending a request if Line1 is empty.
+//Components given in opts are merged with the ones derived from addr, the
+//latter winning on conflicts.
+func (r *requestProcessor) GeocodeStructured(ctx context.Context, addr StructuredAddress, opts ...RequestOption) (GResponse, error) {
+	if addr.Line1 == "" {
+		return GResponse{}, ErrEmptyAddress
+	}
+	address := joinNonEmpty(", ", addr.Line1, addr.Line2)
+	return r.GeocodeContext(ctx, address, append(opts[:len(opts):len(opts)], WithComponents(addr.components()))...)
+}
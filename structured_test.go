@@ -0,0 +1,44 @@
+package geopard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGeocodeStructured(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		q := req.URL.Query()
+		if got, want := q.Get("address"), "Unter den Linden 1, Hinterhaus"; got != want {
+			t.Errorf("address = %q, want %q", got, want)
+		}
+		if got, want := q.Get("components"), "country:DE|locality:Berlin|postal_code:10117"; got != want {
+			t.Errorf("components = %q, want %q", got, want)
+		}
+		w.Write([]byte(testResponse))
+	}))
+	defer srv.Close()
+	r := New(Options{BaseURL: srv.URL + "/?"})
+	defer r.Close()
+
+	addr := StructuredAddress{Line1: "Unter den Linden 1", Line2: "Hinterhaus", City: "Berlin", PostalCode: "10117", Country: "DE"}
+	if _, err := r.GeocodeStructured(context.Background(), addr); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := r.GeocodeStructured(context.Background(), StructuredAddress{City: "Berlin"})
+	if !errors.Is(err, ErrEmptyAddress) {
+		t.Errorf("got %v, want ErrEmptyAddress", err)
+	}
+	if errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("local check reported as INVALID_REQUEST")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server got %d requests, want 1", got)
+	}
+}
@@ -0,0 +1,31 @@
+package geopard
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+//DefaultTransport returns the http transport used if Options.Client is nil.
+//It is a clone of http.DefaultTransport tuned for sending many requests to
+//the single geocoding host:
+//
+//	MaxIdleConnsPerHost  32 instead of 2, so bursts of concurrent requests
+//	                     reuse connections instead of opening new ones
+//	MaxIdleConns         32, nearly all requests go to one host anyway
+//	IdleConnTimeout      90s, keeps connections across refill periods of
+//	                     the rate limiter
+//	KeepAlive            30s TCP keep-alive probes
+//
+//It can be used as a starting point for a custom client.
+func DefaultTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	t.MaxIdleConns = 32
+	t.MaxIdleConnsPerHost = 32
+	t.IdleConnTimeout = 90 * time.Second
+	return t
+}
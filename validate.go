@@ -0,0 +1,116 @@
+package geopard
+
+import (
+	"context"
+	"errors"
+)
+
+//AddressValidation is the outcome of ValidateAddress.
+type AddressValidation struct {
+	//Valid is true if the best result satisfies the validation policy.
+	Valid bool
+	//Corrected is the formatted address of the best result.
+	Corrected string
+	//PartialMatch is true if the best result only partially matches the
+	//queried address.
+	PartialMatch bool
+	//Precision is the location type of the best result, e.g. LocationRooftop.
+	Precision string
+	//Reason tells why an address is invalid, one of the Reason...
+	//constants. It is empty for valid addresses.
+	Reason string
+}
+
+//Reasons for an invalid address, see AddressValidation.Reason.
+const (
+	ReasonZeroResults  = "zero results"
+	ReasonPartialMatch = "partial match"
+	ReasonImprecise    = "imprecise location"
+)
+
+type validationPolicy struct {
+	allowPartial bool
+	minPrecision string
+}
+
+//reject returns the reason why the policy rejects res or an empty string
+//if it accepts it.
+func (p validationPolicy) reject(res GResult) string {
+	if res.PartialMatch && !p.allowPartial {
+		return ReasonPartialMatch
+	}
+	if locationTypeRank(res.Geometry.LocationType) < locationTypeRank(p.minPrecision) {
+		return ReasonImprecise
+	}
+	return ""
+}
+
+//ValidateAddress geocodes the given address and judges the first (best)
+//result. By default an address is valid if it is not a partial match and
+//its location is more precise than approximate. Both thresholds can be
+//changed with Options.ValidationAllowPartial and Options.ValidationMinPrecision.
+//An address that yields no results, or only partial matches dropped by
+//Options.RejectPartialMatch, is reported as invalid with the Reason set
+//instead of an error. The error is reserved for failed requests.
+func (r *requestProcessor) ValidateAddress(ctx context.Context, address string, opts ...RequestOption) (AddressValidation, error) {
+	resp, err := r.GeocodeContext(ctx, address, opts...)
+	if errors.Is(err, ErrZeroResults) {
+		return AddressValidation{Reason: ReasonZeroResults}, nil
+	}
+	if errors.Is(err, ErrPartialMatch) {
+		return AddressValidation{PartialMatch: true, Reason: ReasonPartialMatch}, nil
+	}
+	if err != nil {
+		return AddressValidation{}, err
+	}
+	if len(resp.Results) == 0 {
+		return AddressValidation{Reason: ReasonZeroResults}, nil
+	}
+
+	best := resp.Results[0]
+	reason := r.validation.reject(best)
+	return AddressValidation{
+		Valid:        reason == "",
+		Corrected:    best.FormattedAddr,
+		PartialMatch: best.PartialMatch,
+		Precision:    best.Geometry.LocationType,
+		Reason:       reason,
+	}, nil
+}
+
+//GeocodeUnique geocodes the given address and returns its result only if it
+//is unambiguous. It fails with ErrZeroResults if there is no result and with
+//ErrAmbiguous if there is more than one. Partial matches count like any other
+//result; set Options.RejectPartialMatch to drop them before counting.
+func (r *requestProcessor) GeocodeUnique(ctx context.Context, address string, opts ...RequestOption) (GResult, error) {
+	resp, err := r.GeocodeContext(ctx, address, opts...)
+	if err != nil {
+		return GResult{}, err
+	}
+
+	switch len(resp.Results) {
+	case 0:
+		return GResult{}, ErrZeroResults
+	case 1:
+		return resp.Results[0], nil
+	}
+	return GResult{}, ErrAmbiguous
+}
+
+//Confirm reverse geocodes p and reports whether expectedPlaceID is among
+//the results. It can be used to check that a stored location still refers
+//to the same place. Errors, including ErrZeroResults, are returned
+//unchanged.
+func (r *requestProcessor) Confirm(ctx context.Context, p GPoint, expectedPlaceID PlaceID) (bool, error) {
+	resp, err := r.ReverseGeocodeContext(ctx, p.Lat, p.Lng)
+	if err != nil {
+		return false, err
+	}
+
+	for _, res := range resp.Results {
+		if res.PlaceId == expectedPlaceID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,55 @@
+package geopard
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateAddress(t *testing.T) {
+	const partial = `{"status":"OK","results":[{"formatted_address":"Berlin","partial_match":true,"geometry":{"location_type":"ROOFTOP"}}]}`
+	const approximate = `{"status":"OK","results":[{"formatted_address":"Berlin","geometry":{"location_type":"APPROXIMATE"}}]}`
+	tests := []struct {
+		name string
+		body string
+		opts Options
+		want AddressValidation
+	}{
+		{"valid", testResponse, Options{},
+			AddressValidation{Valid: true, Corrected: "Unter den Linden 1, 10117 Berlin, Germany", Precision: LocationRooftop}},
+		{"partial", partial, Options{},
+			AddressValidation{Corrected: "Berlin", PartialMatch: true, Precision: LocationRooftop, Reason: ReasonPartialMatch}},
+		{"partial rejected", partial, Options{RejectPartialMatch: true},
+			AddressValidation{PartialMatch: true, Reason: ReasonPartialMatch}},
+		{"partial allowed", partial, Options{ValidationAllowPartial: true},
+			AddressValidation{Valid: true, Corrected: "Berlin", PartialMatch: true, Precision: LocationRooftop}},
+		{"imprecise", approximate, Options{},
+			AddressValidation{Corrected: "Berlin", Precision: LocationApproximate, Reason: ReasonImprecise}},
+		{"zero results", `{"status":"ZERO_RESULTS","results":[]}`, Options{},
+			AddressValidation{Reason: ReasonZeroResults}},
+	}
+	for _, tt := range tests {
+		srv := staticServer(tt.body)
+		tt.opts.BaseURL = srv.URL + "/?"
+		r := New(tt.opts)
+		got, err := r.ValidateAddress(context.Background(), "Unter den Linden 1, Berlin")
+		r.Close()
+		srv.Close()
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateAddressRequestFailed(t *testing.T) {
+	srv := staticServer(`{"status":"REQUEST_DENIED","results":[]}`)
+	defer srv.Close()
+	r := New(Options{BaseURL: srv.URL + "/?"})
+	defer r.Close()
+	if _, err := r.ValidateAddress(context.Background(), "Berlin"); !errors.Is(err, ErrRequestDenied) {
+		t.Errorf("got %v, want ErrRequestDenied", err)
+	}
+}